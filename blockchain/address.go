@@ -0,0 +1,148 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+const (
+	// AddressHashLen - Length of the public key hash in an address
+	// (in bytes)
+	AddressHashLen = 20
+
+	// addressChecksumLen - Length of the checksum at the end of
+	// an address (in bytes)
+	addressChecksumLen = 4
+
+	// base58Alphabet - The digits of Base58, which leave out 0, O, I
+	// and l so addresses can't be misread
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+)
+
+/************************************
+ * Base58
+************************************/
+
+// base58Encode - Encodes bytes in Base58. Every leading zero
+// byte becomes a leading 1
+func base58Encode(data []byte) string {
+	var out []byte
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+
+	// The digits came out least significant first
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// base58Decode - Decodes a Base58 string
+func base58Decode(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for i := 0; i < len(s); i++ {
+		digit := bytes.IndexByte([]byte(base58Alphabet), s[i])
+		if digit < 0 {
+			return nil, errors.New("base58Decode: invalid character")
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
+
+/************************************
+ * Addresses
+************************************/
+
+// AddressHash - Returns the hash of an account that its addresses
+// carry. This works for any account: public keys as well as
+// multisig, script and contract accounts
+func AddressHash(pubKey *ecdsa.PublicKey) []byte {
+	hash := sha256.Sum256([]byte(accountKey(pubKey.X, pubKey.Y)))
+	return hash[:AddressHashLen]
+}
+
+// addressChecksum - The first bytes of a double SHA 256 of the
+// version byte and the hash
+func addressChecksum(payload []byte) []byte {
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	return second[:addressChecksumLen]
+}
+
+// EncodeAddress - Turns the hash of an account into a Base58Check
+// address: the address version of the chain parameters, the hash
+// and a checksum
+func EncodeAddress(hash []byte) string {
+	payload := append([]byte{params.AddressVersion}, hash...)
+	return base58Encode(append(payload, addressChecksum(payload)...))
+}
+
+// PublicKeyAddress - Returns the address of an account
+func PublicKeyAddress(pubKey *ecdsa.PublicKey) string {
+	return EncodeAddress(AddressHash(pubKey))
+}
+
+// DecodeAddress - Returns the hash carried by an address. Fails if
+// the address is mistyped or belongs to a different chain
+func DecodeAddress(address string) ([]byte, error) {
+	data, err := base58Decode(address)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != 1+AddressHashLen+addressChecksumLen {
+		return nil, errors.New("DecodeAddress: address has the wrong length")
+	}
+
+	payload := data[:1+AddressHashLen]
+	if bytes.Compare(addressChecksum(payload), data[1+AddressHashLen:]) != 0 {
+		return nil, errors.New("DecodeAddress: checksum doesn't match")
+	}
+	if payload[0] != params.AddressVersion {
+		return nil, errors.New("DecodeAddress: address belongs to a different chain")
+	}
+	return payload[1:], nil
+}
+
+// outputAddressHash - Returns the address hash of the output of
+// a transaction, whether it pays an address or a public key
+func (t *Transaction) outputAddressHash() []byte {
+	if t.Recipient != nil {
+		return t.Recipient
+	}
+	return AddressHash(&ecdsa.PublicKey{X: t.XOutput, Y: t.YOutput})
+}
+
+// PayToAddress - Makes the transaction pay an address instead of
+// a public key
+func (t *Transaction) PayToAddress(address string) error {
+	hash, err := DecodeAddress(address)
+	if err != nil {
+		return err
+	}
+	t.Recipient = hash
+	t.XOutput = nil
+	t.YOutput = nil
+	return nil
+}
@@ -0,0 +1,75 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestBase58(t *testing.T) {
+	vectors := map[string]string{
+		"":                       "",
+		"61":                     "2g",
+		"626262":                 "a3gV",
+		"68656c6c6f20776f726c64": "StV1DL6CwTryKyV",
+		"00000000000000000000":   "1111111111",
+		"0000287fb4cd":           "11233QC4",
+	}
+	for data, encoded := range vectors {
+		raw, _ := hex.DecodeString(data)
+		if got := base58Encode(raw); got != encoded {
+			t.Fatalf("base58Encode(%s) = %s, want %s", data, got, encoded)
+		}
+		decoded, err := base58Decode(encoded)
+		if err != nil || !bytes.Equal(decoded, raw) {
+			t.Fatalf("base58Decode(%s) = %x, %v, want %s", encoded, decoded, err, data)
+		}
+	}
+	for _, s := range []string{"0", "O", "I", "l", "ab+"} {
+		if _, err := base58Decode(s); err == nil {
+			t.Fatalf("base58Decode(%s) accepted", s)
+		}
+	}
+}
+
+func TestAddresses(t *testing.T) {
+	defer SetChainParams(ActiveChainParams())
+	p := DefaultChainParams()
+	p.AddressVersion = 0
+	SetChainParams(p)
+
+	// The worked example of a Bitcoin address
+	hash, _ := hex.DecodeString("010966776006953d5567439e5e39f86a0d273bee")
+	if address := EncodeAddress(hash); address != "16UwLL9Risc3QfPqBUvKofHmBQ7wMtjvM" {
+		t.Fatalf("EncodeAddress = %s", address)
+	}
+
+	key := testKey(t)
+	address := PublicKeyAddress(&key.PublicKey)
+	decoded, err := DecodeAddress(address)
+	if err != nil || !bytes.Equal(decoded, AddressHash(&key.PublicKey)) {
+		t.Fatalf("DecodeAddress = %x, %v", decoded, err)
+	}
+
+	// Every mistyped character gets caught by the checksum
+	for i := 0; i < len(address); i++ {
+		typo := []byte(address)
+		if typo[i] == '2' {
+			typo[i] = '3'
+		} else {
+			typo[i] = '2'
+		}
+		if _, err := DecodeAddress(string(typo)); err == nil {
+			t.Fatalf("typo at %d accepted", i)
+		}
+	}
+	if _, err := DecodeAddress(address[1:]); err == nil {
+		t.Fatal("truncated address accepted")
+	}
+
+	p.AddressVersion = 1
+	SetChainParams(p)
+	if _, err := DecodeAddress(address); err == nil {
+		t.Fatal("address of another chain accepted")
+	}
+}
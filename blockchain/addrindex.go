@@ -0,0 +1,134 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"sync"
+)
+
+// AddressTX - A transaction that debits or credits an account,
+// and where it sits on the blockchain
+type AddressTX struct {
+	TXHash   []byte     `json:"TXHash"`
+	Location TXLocation `json:"Location"`
+	Debit    bool       `json:"Debit"`
+	Credit   bool       `json:"Credit"`
+}
+
+// AddressIndex - Maps every account to the transactions that debit
+// or credit it, oldest first. Accounts are keyed by their address
+// hash, so payments to an address and to the public key behind it
+// end up in the same history. It's optional: register it with
+// RegisterIndexer to build it up as blocks get connected.
+// Unstakes show up as credits at the height they went in, even
+// though the coins only come back UnbondingDelay blocks later.
+// What settled channels pay out isn't in the history, since who
+// gets paid depends on the channel ledger and not on the settling
+// transaction: CalcChannelPayoutsOnBC has it
+type AddressIndex struct {
+	entries map[string][]AddressTX
+	mux     sync.RWMutex
+}
+
+// MakeAddressIndex - AddressIndex constructor
+func MakeAddressIndex() *AddressIndex {
+	return &AddressIndex{entries: make(map[string][]AddressTX)}
+}
+
+// ConnectBlock - Appends every transaction of the block to the
+// history of the accounts it debits or credits
+func (ix *AddressIndex) ConnectBlock(b *Block, height uint64) error {
+	ix.mux.Lock()
+	defer ix.mux.Unlock()
+	for i := range b.TXs {
+		tx := &b.TXs[i]
+		entry := AddressTX{
+			TXHash:   tx.HashTransaction(),
+			Location: TXLocation{BlockHash: b.Hash, Height: height, Index: i},
+		}
+
+		input := string(AddressHash(&ecdsa.PublicKey{X: tx.XInput, Y: tx.YInput}))
+		output := string(tx.outputAddressHash())
+
+		// Claims and refunds pay the input out of a lock and unstakes
+		// pay it back its stake, so the input gets credited and the
+		// output doesn't get anything
+		if tx.Type == TXHTLCClaim || tx.Type == TXHTLCRefund || tx.Type == TXUnstake {
+			entry.Credit = true
+			ix.entries[input] = append(ix.entries[input], entry)
+			continue
+		}
+		if input == output {
+			entry.Debit = true
+			entry.Credit = true
+			ix.entries[input] = append(ix.entries[input], entry)
+			continue
+		}
+		if tx.XInput != nil && tx.YInput != nil {
+			debit := entry
+			debit.Debit = true
+			ix.entries[input] = append(ix.entries[input], debit)
+		}
+		if tx.Recipient != nil || (tx.XOutput != nil && tx.YOutput != nil) {
+			credit := entry
+			credit.Credit = true
+			ix.entries[output] = append(ix.entries[output], credit)
+		}
+	}
+	return nil
+}
+
+// DisconnectBlock - Removes every transaction of the block from
+// the history of the accounts it touched
+func (ix *AddressIndex) DisconnectBlock(b *Block, height uint64) error {
+	ix.mux.Lock()
+	defer ix.mux.Unlock()
+	for i := range b.TXs {
+		tx := &b.TXs[i]
+		input := string(AddressHash(&ecdsa.PublicKey{X: tx.XInput, Y: tx.YInput}))
+		output := string(tx.outputAddressHash())
+		for _, key := range []string{input, output} {
+			history := ix.entries[key]
+
+			// Blocks get disconnected tip first, so the entries of
+			// this block are at the end of the history
+			n := len(history)
+			for n > 0 && bytes.Compare(history[n-1].Location.BlockHash, b.Hash) == 0 {
+				n--
+			}
+			if n == 0 {
+				delete(ix.entries, key)
+			} else {
+				ix.entries[key] = history[:n]
+			}
+		}
+	}
+	return nil
+}
+
+// AddressHistory - Returns up to limit transactions of the account,
+// oldest first, skipping the first offset of them, along with how
+// many transactions the account has in total
+func (ix *AddressIndex) AddressHistory(pubKey *ecdsa.PublicKey, offset int, limit int) ([]AddressTX, int) {
+	ix.mux.RLock()
+	defer ix.mux.RUnlock()
+	history := ix.entries[string(AddressHash(pubKey))]
+	total := len(history)
+
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= total || limit <= 0 {
+		return []AddressTX{}, total
+	}
+	// Clamp the limit before adding it, so a huge one can't
+	// overflow the end of the page
+	if limit > total-offset {
+		limit = total - offset
+	}
+	end := offset + limit
+
+	page := make([]AddressTX, end-offset)
+	copy(page, history[offset:end])
+	return page, total
+}
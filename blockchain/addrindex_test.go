@@ -0,0 +1,115 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestAddressIndex(t *testing.T) {
+	ix := MakeAddressIndex()
+	RegisterIndexer(ix)
+	defer UnregisterIndexer(ix)
+
+	treasury := testKey(t)
+	alice := testKey(t)
+	bob := testKey(t)
+	funding := transfer(t, treasury, &alice.PublicKey, 10)
+	bc := genesisChain(t, funding)
+	connectBlock(&bc[0], 0)
+
+	// A payment to bob's address and one to his public key both
+	// belong in his history
+	toAddress := transfer(t, alice, &bob.PublicKey, 1)
+	if err := toAddress.PayToAddress(PublicKeyAddress(&bob.PublicKey)); err != nil {
+		t.Fatal(err)
+	}
+	signTX(t, &toAddress, alice)
+	toKey := transfer(t, alice, &bob.PublicKey, 2)
+	toSelf := transfer(t, alice, &alice.PublicKey, 3)
+	b := nextBlock(t, &bc, toAddress, toKey, toSelf)
+	if !bc.AddBlock(&b) {
+		t.Fatal("block rejected")
+	}
+
+	history, total := ix.AddressHistory(&bob.PublicKey, 0, 10)
+	if total != 2 || len(history) != 2 {
+		t.Fatalf("bob has %d transactions, want 2", total)
+	}
+	for i, tx := range []Transaction{toAddress, toKey} {
+		if !bytes.Equal(history[i].TXHash, tx.HashTransaction()) || !history[i].Credit || history[i].Debit {
+			t.Fatalf("bob's transaction %d = %+v", i, history[i])
+		}
+		if history[i].Location.Height != 1 || history[i].Location.Index != i {
+			t.Fatalf("bob's transaction %d is at %+v", i, history[i].Location)
+		}
+	}
+
+	// Alice got funded, paid twice and paid herself once
+	history, total = ix.AddressHistory(&alice.PublicKey, 0, 10)
+	if total != 4 || !history[0].Credit || history[0].Debit || !history[1].Debit {
+		t.Fatalf("alice's history = %+v", history)
+	}
+	if !history[3].Debit || !history[3].Credit {
+		t.Fatal("payment to herself isn't both a debit and a credit")
+	}
+
+	page, total := ix.AddressHistory(&alice.PublicKey, 1, 2)
+	if total != 4 || len(page) != 2 || !bytes.Equal(page[0].TXHash, toAddress.HashTransaction()) {
+		t.Fatalf("page = %+v", page)
+	}
+	if page, _ := ix.AddressHistory(&alice.PublicKey, 4, 2); len(page) != 0 {
+		t.Fatal("page past the end isn't empty")
+	}
+
+	disconnectBlock(&bc[1], 1)
+	if _, total := ix.AddressHistory(&bob.PublicKey, 0, 10); total != 0 {
+		t.Fatalf("bob has %d transactions after the disconnect, want 0", total)
+	}
+	if _, total := ix.AddressHistory(&alice.PublicKey, 0, 10); total != 1 {
+		t.Fatalf("alice has %d transactions after the disconnect, want 1", total)
+	}
+}
+
+func TestAddressIndexPayouts(t *testing.T) {
+	ix := MakeAddressIndex()
+	RegisterIndexer(ix)
+	defer UnregisterIndexer(ix)
+
+	treasury := testKey(t)
+	alice := testKey(t)
+	bob := testKey(t)
+	bc := genesisChain(t, transfer(t, treasury, &alice.PublicKey, 10))
+	connectBlock(&bc[0], 0)
+
+	preimage := []byte("secret")
+	hashLock := sha256.Sum256(preimage)
+	lock := htlcTX(t, TXHTLCLock, alice, &bob.PublicKey, 5, HTLCPayload{HashLock: hashLock[:], Deadline: 100})
+	b := nextBlock(t, &bc, lock, stakingTX(t, TXStake, alice, 2, nil))
+	if !bc.AddBlock(&b) {
+		t.Fatal("block rejected")
+	}
+
+	// The claim names alice as its output, but only pays bob
+	claim := htlcTX(t, TXHTLCClaim, bob, &alice.PublicKey, 5, HTLCPayload{LockTX: lock.HashTransaction(), Preimage: preimage})
+	unstake := stakingTX(t, TXUnstake, alice, 1, nil)
+	b = nextBlock(t, &bc, claim, unstake)
+	if !bc.AddBlock(&b) {
+		t.Fatal("block rejected")
+	}
+
+	history, total := ix.AddressHistory(&bob.PublicKey, 0, 10)
+	if total != 2 || !bytes.Equal(history[1].TXHash, claim.HashTransaction()) || !history[1].Credit || history[1].Debit {
+		t.Fatalf("bob's history = %+v", history)
+	}
+
+	// Funding, lock, stake and unstake, with a limit big enough to
+	// overflow past the end of the page
+	history, total = ix.AddressHistory(&alice.PublicKey, 1, int(^uint(0)>>1))
+	if total != 4 || len(history) != 3 {
+		t.Fatalf("alice has %d transactions, page of %d", total, len(history))
+	}
+	if !bytes.Equal(history[2].TXHash, unstake.HashTransaction()) || !history[2].Credit || history[2].Debit {
+		t.Fatalf("alice's unstake = %+v", history[2])
+	}
+}
@@ -3,15 +3,14 @@ package blockchain
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"crypto/elliptic"
 	crand "crypto/rand"
-	"crypto/sha256"
 	"encoding/base64"
 	"encoding/binary"
 	"fmt"
+	"math/big"
 	"math/rand"
 	"os"
-	"strconv"
-	"strings"
 	"sync"
 )
 
@@ -40,12 +39,21 @@ const (
 // of these blocks
 type Block struct {
 	/*Block headers*/
+	Version    uint32 `json:"Version"`
 	Index      uint64 `json:"Index"`
 	Hash       []byte `json:"Hash"`
 	PrevHash   []byte `json:"PrevHash"`
 	Timestamp  uint64 `json:"Timestamp"`
 	Difficulty uint32 `json:"Difficulty"`
 	Nonce      []byte `json:"Nonce"`
+	StateRoot  []byte `json:"StateRoot"`
+	MerkleRoot []byte `json:"MerkleRoot"`
+
+	/*Block signature (only used by signing consensus engines)*/
+	XSigner    *big.Int `json:"XSigner"`
+	YSigner    *big.Int `json:"YSigner"`
+	RSignature *big.Int `json:"RSignature"`
+	SSignature *big.Int `json:"SSignature"`
 
 	/*Transaction data*/
 	TXs []Transaction `json:"TXs"`
@@ -117,16 +125,7 @@ func (bc *Blockchain) CalcAccountBalanceOnBC(pubKey *ecdsa.PublicKey, index int6
 	var count int64 = 0
 	for _, block := range *bc {
 		for _, tx := range block.TXs {
-			if strings.Compare(pubKey.X.String(), tx.XInput.String()) == 0 {
-				if strings.Compare(pubKey.Y.String(), tx.YInput.String()) == 0 {
-					totalBalance -= tx.Amount
-				}
-			}
-			if strings.Compare(pubKey.X.String(), tx.XOutput.String()) == 0 {
-				if strings.Compare(pubKey.Y.String(), tx.YOutput.String()) == 0 {
-					totalBalance += tx.Amount
-				}
-			}
+			totalBalance += tx.balanceDelta(pubKey)
 		}
 
 		if index > 0 {
@@ -137,6 +136,11 @@ func (bc *Blockchain) CalcAccountBalanceOnBC(pubKey *ecdsa.PublicKey, index int6
 		}
 	}
 
+	// Add the stake that has finished unbonding and
+	// what settled payment channels paid out
+	totalBalance += bc.CalcUnbondedOnBC(pubKey, index)
+	totalBalance += bc.CalcChannelPayoutsOnBC(pubKey, index)
+
 	return totalBalance
 }
 
@@ -149,16 +153,7 @@ func CalcAccountBalanceOnTXPool(pubKey *ecdsa.PublicKey, txpool []Transaction) f
 	// Next, get the balance of the person within the current transaction
 	// pool and add it to the totalBalance
 	for _, tx := range txpool {
-		if strings.Compare(pubKey.X.String(), tx.XInput.String()) == 0 {
-			if strings.Compare(pubKey.Y.String(), tx.YInput.String()) == 0 {
-				totalBalance -= tx.Amount
-			}
-		}
-		if strings.Compare(pubKey.X.String(), tx.XOutput.String()) == 0 {
-			if strings.Compare(pubKey.Y.String(), tx.YOutput.String()) == 0 {
-				totalBalance += tx.Amount
-			}
-		}
+		totalBalance += tx.balanceDelta(pubKey)
 	}
 
 	return totalBalance
@@ -176,20 +171,25 @@ func (bc *Blockchain) AddTransaction(t Transaction) {
 }
 
 // AddBlock - This takes a block and adds it to the blockchain if it
-// proves to be valid. Returns true if block was added. Returns
+// proves to be valid under the current consensus engine. Returns true if block was added. Returns
 // false if block wasn't added.
+// The previous hash of the block has to be the hash of the
+// last block. HashFormatV2 covers the previous hash, so under it
+// the proof of work commits to the link too.
 // NOTE: This function should only be called on the second block
 // of the blockchain and on
 func (bc *Blockchain) AddBlock(b *Block) bool {
-	if b.BlockHashIsValid() {
-		var txpool []Transaction
-		if bc.BlockIsValid(b, txpool) {
-			b.PrevHash = (*bc)[len(*bc)-1].Hash
-			(*bc) = append(*bc, *b)
-			return true
-		}
+	if bytes.Compare(b.PrevHash, (*bc)[len(*bc)-1].Hash) != 0 {
 		return false
 	}
+	var txpool []Transaction
+	if bc.BlockIsValid(b, txpool) {
+		chainMux.Lock()
+		(*bc) = append(*bc, *b)
+		chainMux.Unlock()
+		connectBlock(&(*bc)[len(*bc)-1], uint64(len(*bc)-1))
+		return true
+	}
 	return false
 }
 
@@ -198,42 +198,19 @@ func (bc *Blockchain) AddBlock(b *Block) bool {
 **********************************/
 
 // HashBlock - Generates a hash to a block in the blockchain,
-// then returns it as a byte slice
+// then returns it as a byte slice. This is a single SHA-256, so
+// it is cheap enough to identify and index blocks with. The
+// proof of work is checked against PoWHash of this hash. The
+// rule set active at the index of the block picks the hash
+// format: HashFormatV1 is the original hash over the
+// transactions, HashFormatV2 only covers the header, whose
+// Merkle root has to be filled in first
 func (b *Block) HashBlock() []byte {
-	// here is the buffer that stores the data temporarily
-	var buff string
-
-	// write the block headers to the buffer
-	buff += strconv.FormatUint(b.Timestamp, Base)
-	buff += strconv.FormatUint(uint64(b.Difficulty), Base)
-	buff += string(b.Nonce)
-
-	// write all the transactions to the buffer
-	for _, tx := range b.TXs {
-		// write the version
-		buff += strconv.FormatUint(uint64(tx.Version), Base)
-
-		// write the input, output, and amount
-		buff += tx.XInput.String()
-		buff += tx.YInput.String()
-		buff += tx.XOutput.String()
-		buff += tx.YOutput.String()
-		buff += strconv.FormatFloat(tx.Amount, 'f', -1, 64)
-
-		// write the timestamp and extra data
-		buff += strconv.FormatUint(tx.Timestamp, Base)
-		buff += string(tx.Data)
-
-		// write the signature
-		buff += tx.RSignature.String()
-		buff += tx.SSignature.String()
+	if RuleSetAt(b.Index).HashFormat == HashFormatV2 {
+		header := b.Header()
+		return header.hashHeaderV2()
 	}
-
-	// now, hash that buffer, assign it to the block,
-	// and return it
-	hasher := sha256.New()
-	hasher.Write([]byte(buff))
-	return hasher.Sum(nil)
+	return b.hashBlockV1()
 }
 
 // MineBlock - This takes a block and hashes and updates
@@ -244,18 +221,21 @@ func (b *Block) HashBlock() []byte {
 func (b *Block) MineBlock() []byte {
 	var nonce []byte
 	var count int64 = 0
+	b.MerkleRoot = CalcMerkleRoot(b.TXs)
 	SeedRand()
 	for {
 		// First, Generate a nonce
 		nonce = GenRandBytes(32)
 		b.Nonce = nonce
 
-		// Second, hash the block
+		// Second, hash the block, then run the proof of work
+		// hash selected by the chain parameters over it
 		b.Hash = b.HashBlock()
+		powHash := PoWHash(b.Hash)
 
 		// Next, check how many bytes that are equal to zero there are in a row
 		var numZero uint32 = 0
-		for _, v := range b.Hash {
+		for _, v := range powHash {
 			if v != 0 {
 				break
 			}
@@ -279,41 +259,75 @@ func (b *Block) MineBlock() []byte {
 	return nonce
 }
 
+/********************************
+ * Block signing functions
+********************************/
+
+// SealHash - Returns the hash a block signer signs: HashFormatV2 of
+// the header, whatever the hash format of the block. It covers the
+// index, previous hash and signer, which HashFormatV1 doesn't, so
+// a signature can't be moved to another height
+func (b *Block) SealHash() []byte {
+	header := b.Header()
+	return header.hashHeaderV2()
+}
+
+// SignBlock - Sets the signer of the block to the public key
+// of the private key given, hashes the block and signs
+// its SealHash. The signature itself isn't part of either hash
+func (b *Block) SignBlock(key *ecdsa.PrivateKey) error {
+	b.XSigner = key.X
+	b.YSigner = key.Y
+	b.MerkleRoot = CalcMerkleRoot(b.TXs)
+	b.Hash = b.HashBlock()
+
+	r, s, err := ecdsa.Sign(crand.Reader, key, b.SealHash())
+	if err != nil {
+		return err
+	}
+	b.RSignature = r
+	b.SSignature = s
+	return nil
+}
+
+// BlockSignatureIsValid - Returns true if the block carries a signer,
+// its hash is correct and the signature over its SealHash was
+// made by the signer
+func (b *Block) BlockSignatureIsValid() bool {
+	if b.XSigner == nil || b.YSigner == nil || b.RSignature == nil || b.SSignature == nil {
+		return false
+	}
+	if bytes.Compare(b.HashBlock(), b.Hash) != 0 {
+		return false
+	}
+	pubKey := &ecdsa.PublicKey{Curve: elliptic.P384(), X: b.XSigner, Y: b.YSigner}
+	return ecdsa.Verify(pubKey, b.SealHash(), b.RSignature, b.SSignature)
+}
+
 /********************************
  * Block validation functions
 ********************************/
 
-// BlockHashIsValid - Returns true if the hash of the block is valid and meets
-// the difficulty
+// BlockHashIsValid - Returns true if the hash of the block is valid and
+// its proof of work hash meets the difficulty
 func (b *Block) BlockHashIsValid() bool {
-	// Shallow copy the struct and deep
-	// copy the slice
-	var bCopy Block
-	bCopy = *b
-	copy(bCopy.Hash, b.Hash)
-
-	bCopy.Hash = bCopy.HashBlock()
-	if bytes.Compare(bCopy.Hash, b.Hash) == 0 {
-		// Get the number of prefixing zeroes
-		var numZero uint32 = 0
-		for _, v := range bCopy.Hash {
-			if v != 0 {
-				break
-			}
-			numZero++
-		}
-
-		// If the number of prefixing zeroes matches
-		// the number of zeroes needed, which
-		// is specified by the difficulty,
-		// then return true. Otherwise, return false
-		if numZero == bCopy.Difficulty {
-			return true
-		}
+	if bytes.Compare(b.HashBlock(), b.Hash) != 0 {
 		return false
 	}
+	return powIsValid(b.Hash, b.Difficulty)
+}
 
-	return false
+// powIsValid - Returns true if the proof of work hash of a block
+// hash starts with exactly difficulty zero bytes
+func powIsValid(hash []byte, difficulty uint32) bool {
+	var numZero uint32 = 0
+	for _, v := range PoWHash(hash) {
+		if v != 0 {
+			break
+		}
+		numZero++
+	}
+	return numZero == difficulty
 }
 
 // RemoveTransaction - Removes a transaction from
@@ -323,79 +337,24 @@ func RemoveTransaction(TXs []Transaction, index int) []Transaction {
 }
 
 // BlockIsValid - This checks to see if all the data in the block is
-// valid other than its previous hash, which is supposed to be
-// set when finally adding a block to the blockchain.
-// If an individual transaction is invalid in the block,
-// that transaction gets removed and the function still returns
-// true.
+// valid as the next block of the blockchain: the hash, the seal,
+// the link to the last block, the timestamp, the Merkle and state
+// roots and every transaction signature, balance and type rule.
+// A block with a single invalid transaction is invalid.
 // If you would like to not factor in the current transaction pool,
 // please pass an empty slice
 // (@TODO-OPTIMIZE)
 func (bc *Blockchain) BlockIsValid(b *Block, txpool []Transaction) bool {
-	var invalidTXIndicies []int
-
-	// First, check the block hash
-	if b.BlockHashIsValid() {
-		// Validate the transaction signatures in the blockchain
-		for i, tx := range b.TXs {
-			if tx.TransactionSignatureIsValid() == false {
-				invalidTXIndicies = append(invalidTXIndicies, i)
-			}
-		}
-
-		// Check to see the cost of every single transaction and if
-		// the person who paid for it has enough money to do so
-		for i, tx := range b.TXs {
-			if tx.TransactionCostIsValid(bc, txpool, -1) == false {
-				invalidTXIndicies = append(invalidTXIndicies, i)
-			}
-		}
-
-		// Remove duplicate invalid transaction indexes
-		invalidTXIndicies = Unique(invalidTXIndicies)
-
-		// Remove all invalid transactions:
-		for _, invalid := range invalidTXIndicies {
-			RemoveTransaction(b.TXs, invalid)
-		}
-
-		return true
-	}
-
-	return false
+	return bc.checkBlock(b, txpool) == ""
 }
 
 // BlockInBlockchainIsValid - Checks to see if a specific index
-// of a block in the blockchain is valid
+// of a block in the blockchain is valid against the blocks below it
 // (@TODO-OPTIMIZE)
 func (bc *Blockchain) BlockInBlockchainIsValid(index int64) bool {
-	for i, b := range *bc {
-		// First check if the hash is valid
-		if b.BlockHashIsValid() {
-			// Next, check every single transaction signature in the block
-			// and also check if the person who paid in the transaction had
-			// enough money to do so (to prevent double spending)
-			var txpool []Transaction
-			for _, tx := range b.TXs {
-				if tx.TransactionSignatureIsValid() == false {
-					return false
-				}
-				if tx.TransactionCostIsValid(bc, txpool, index) == false {
-					return false
-				}
-			}
-
-			// Next, for every other block other than genesis,
-			// check its previous hahs
-			if i > 0 {
-				if bytes.Compare(b.Hash, (*bc)[i-1].PrevHash) != 0 {
-					return false
-				}
-			}
-		}
-
+	snapshot := bc.Snapshot()
+	if index < 0 || index >= int64(len(snapshot)) {
 		return false
 	}
-
-	return true
+	return snapshot.verifyBlock(uint64(index)) == ""
 }
@@ -0,0 +1,282 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/json"
+	"math/big"
+)
+
+// ChannelState - An off-chain balance update of a payment channel.
+// Paid is the total the recipient has been paid so far, and every
+// update has a higher Sequence than the last. The sender signs
+// every update; the recipient only signs for a cooperative close
+type ChannelState struct {
+	ChannelID  []byte   `json:"ChannelID"`
+	Sequence   uint64   `json:"Sequence"`
+	Paid       float64  `json:"Paid"`
+	RSender    *big.Int `json:"RSender"`
+	SSender    *big.Int `json:"SSender"`
+	RRecipient *big.Int `json:"RRecipient"`
+	SRecipient *big.Int `json:"SRecipient"`
+}
+
+// ChannelPayload - What goes in the Data of a channel transaction.
+// An open sets DisputeWindow, everything else sets ChannelID, and
+// closes, disputes and cooperative closes carry a State
+type ChannelPayload struct {
+	DisputeWindow uint64        `json:"DisputeWindow"`
+	ChannelID     []byte        `json:"ChannelID"`
+	State         *ChannelState `json:"State"`
+}
+
+// Channel - The on-chain state of a payment channel
+type Channel struct {
+	ID            []byte       `json:"ID"`
+	XSender       *big.Int     `json:"XSender"`
+	YSender       *big.Int     `json:"YSender"`
+	XRecipient    *big.Int     `json:"XRecipient"`
+	YRecipient    *big.Int     `json:"YRecipient"`
+	Capacity      float64      `json:"Capacity"`
+	DisputeWindow uint64       `json:"DisputeWindow"`
+	Closing       bool         `json:"Closing"`
+	CloseHeight   int          `json:"CloseHeight"`
+	Settled       bool         `json:"Settled"`
+	State         ChannelState `json:"State"`
+}
+
+// ChannelLedger - Every payment channel on the blockchain, keyed by
+// the hash of the transaction that opened it
+type ChannelLedger map[string]*Channel
+
+/************************************
+ * Channel states
+************************************/
+
+// HashChannelState - Returns a SHA 256 hash of everything in the
+// state other than the signatures. The fields are length prefixed or
+// fixed size, so two different states never hash the same
+func (s *ChannelState) HashChannelState() []byte {
+	var buff hashBuffer
+	buff.putBytes(s.ChannelID)
+	buff.putUint(s.Sequence)
+	buff.putFloat(s.Paid)
+
+	hash := sha256.Sum256(buff)
+	return hash[:]
+}
+
+// SenderSignatureIsValid - Checks that the sender of the
+// channel signed the state
+func (c *Channel) SenderSignatureIsValid(s *ChannelState) bool {
+	if s.RSender == nil || s.SSender == nil {
+		return false
+	}
+	pubKey := &ecdsa.PublicKey{Curve: elliptic.P384(), X: c.XSender, Y: c.YSender}
+	return ecdsa.Verify(pubKey, s.HashChannelState(), s.RSender, s.SSender)
+}
+
+// RecipientSignatureIsValid - Checks that the recipient of the
+// channel signed the state
+func (c *Channel) RecipientSignatureIsValid(s *ChannelState) bool {
+	if s.RRecipient == nil || s.SRecipient == nil {
+		return false
+	}
+	pubKey := &ecdsa.PublicKey{Curve: elliptic.P384(), X: c.XRecipient, Y: c.YRecipient}
+	return ecdsa.Verify(pubKey, s.HashChannelState(), s.RRecipient, s.SRecipient)
+}
+
+// StateIsValid - Checks that a state belongs to the channel, pays no
+// more than the channel holds, and was signed by the sender. The
+// recipient should run this on every update it gets off-chain. The
+// zero state doesn't need a signature, since it pays nothing
+func (c *Channel) StateIsValid(s *ChannelState) bool {
+	if bytes.Compare(s.ChannelID, c.ID) != 0 {
+		return false
+	}
+	if s.Paid < 0 || s.Paid > c.Capacity {
+		return false
+	}
+	if s.Sequence == 0 && s.Paid == 0 {
+		return true
+	}
+	return c.SenderSignatureIsValid(s)
+}
+
+/************************************
+ * Channel ledger
+************************************/
+
+// EncodeChannelPayload - Serializes a payload for the Data of a
+// channel transaction
+func EncodeChannelPayload(p ChannelPayload) ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// DecodeChannelPayload - Deserializes the Data of a channel transaction
+func DecodeChannelPayload(data []byte) (*ChannelPayload, error) {
+	var p ChannelPayload
+	err := json.Unmarshal(data, &p)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// applyChannelTransaction - Applies a channel transaction that is in
+// the block at the given height. Returns false and leaves the ledger
+// alone if the transaction breaks the rules of the channel
+func (l ChannelLedger) applyChannelTransaction(t *Transaction, height int) bool {
+	p, err := DecodeChannelPayload(t.Data)
+	if err != nil {
+		return false
+	}
+
+	if t.Type == TXChannelOpen {
+		if t.Amount <= 0 || p.DisputeWindow == 0 {
+			return false
+		}
+		id := t.HashTransaction()
+		if _, exists := l[string(id)]; exists {
+			return false
+		}
+		l[string(id)] = &Channel{
+			ID:            id,
+			XSender:       t.XInput,
+			YSender:       t.YInput,
+			XRecipient:    t.XOutput,
+			YRecipient:    t.YOutput,
+			Capacity:      t.Amount,
+			DisputeWindow: p.DisputeWindow,
+			State:         ChannelState{ChannelID: id},
+		}
+		return true
+	}
+
+	c, exists := l[string(p.ChannelID)]
+	if !exists || c.Settled {
+		return false
+	}
+	submitter := accountKey(t.XInput, t.YInput)
+	isParty := submitter == accountKey(c.XSender, c.YSender) ||
+		submitter == accountKey(c.XRecipient, c.YRecipient)
+
+	switch t.Type {
+	case TXChannelClose:
+		if c.Closing || !isParty || p.State == nil || !c.StateIsValid(p.State) {
+			return false
+		}
+		c.Closing = true
+		c.CloseHeight = height
+		c.State = *p.State
+	case TXChannelDispute:
+		if !c.Closing || uint64(height-c.CloseHeight) >= c.DisputeWindow {
+			return false
+		}
+		if p.State == nil || !c.StateIsValid(p.State) || p.State.Sequence <= c.State.Sequence {
+			return false
+		}
+		c.State = *p.State
+	case TXChannelSettle:
+		if !c.Closing || uint64(height-c.CloseHeight) < c.DisputeWindow {
+			return false
+		}
+		c.Settled = true
+	case TXChannelCoopClose:
+		if !isParty || p.State == nil || !c.StateIsValid(p.State) {
+			return false
+		}
+		if !c.SenderSignatureIsValid(p.State) || !c.RecipientSignatureIsValid(p.State) {
+			return false
+		}
+		c.State = *p.State
+		c.Settled = true
+	default:
+		return false
+	}
+
+	return true
+}
+
+// CalcChannelsOnBC - Replays every channel transaction of the
+// blockchain. The index parameter works the same way as in
+// CalcAccountBalanceOnBC
+func (bc *Blockchain) CalcChannelsOnBC(index int64) ChannelLedger {
+	ledger := make(ChannelLedger)
+	limit := bc.blockLimit(index)
+	for i := 0; i < limit; i++ {
+		for j := range (*bc)[i].TXs {
+			tx := &(*bc)[i].TXs[j]
+			if tx.Type >= TXChannelOpen && tx.Type <= TXChannelCoopClose {
+				ledger.applyChannelTransaction(tx, i)
+			}
+		}
+	}
+	return ledger
+}
+
+// CalcChannelPayoutsOnBC - Returns what settled payment channels paid
+// out to a public key: the recipient gets Paid and the sender gets
+// the rest. The index parameter works the same way as in
+// CalcAccountBalanceOnBC
+func (bc *Blockchain) CalcChannelPayoutsOnBC(pubKey *ecdsa.PublicKey, index int64) float64 {
+	var total float64 = 0
+	key := accountKey(pubKey.X, pubKey.Y)
+	for _, c := range bc.CalcChannelsOnBC(index) {
+		if !c.Settled {
+			continue
+		}
+		if key == accountKey(c.XRecipient, c.YRecipient) {
+			total += c.State.Paid
+		}
+		if key == accountKey(c.XSender, c.YSender) {
+			total += c.Capacity - c.State.Paid
+		}
+	}
+	return total
+}
+
+// channelOf - Returns the ID of the channel a channel transaction
+// touches: its own hash for an open. Returns nil if the payload
+// doesn't decode
+func (t *Transaction) channelOf() []byte {
+	if t.Type == TXChannelOpen {
+		return t.HashTransaction()
+	}
+	p, err := DecodeChannelPayload(t.Data)
+	if err != nil {
+		return nil
+	}
+	return p.ChannelID
+}
+
+// channelTransactionIsValid - Checks a channel transaction against
+// the channels on the blockchain, as if it went into the next block.
+// The channel transactions in txpool count as if they went in before
+// it, so a channel can't be closed or settled twice in one block
+func (t *Transaction) channelTransactionIsValid(bc *Blockchain, txpool []Transaction) bool {
+	id := t.channelOf()
+	if id == nil {
+		return false
+	}
+
+	// Try the transaction on a copy of the channel it touches
+	ledger := bc.CalcChannelsOnBC(-1)
+	scratch := make(ChannelLedger)
+	if c, exists := ledger[string(id)]; exists {
+		channelCopy := *c
+		scratch[string(id)] = &channelCopy
+	}
+	for i := range txpool {
+		pending := &txpool[i]
+		if pending.Type < TXChannelOpen || pending.Type > TXChannelCoopClose {
+			continue
+		}
+		if bytes.Equal(pending.channelOf(), id) {
+			scratch.applyChannelTransaction(pending, len(*bc))
+		}
+	}
+	return scratch.applyChannelTransaction(t, len(*bc))
+}
@@ -0,0 +1,219 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	crand "crypto/rand"
+	"testing"
+)
+
+// channelTX - Returns a signed channel transaction from key
+func channelTX(t *testing.T, txType uint32, key *ecdsa.PrivateKey, to *ecdsa.PublicKey, amount float64, p ChannelPayload) Transaction {
+	t.Helper()
+	data, err := EncodeChannelPayload(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := transfer(t, key, to, amount)
+	tx.Type = txType
+	tx.Data = data
+	signTX(t, &tx, key)
+	return tx
+}
+
+// channelState - Returns a state of the channel signed by the given keys
+func channelState(t *testing.T, id []byte, sequence uint64, paid float64, sender, recipient *ecdsa.PrivateKey) *ChannelState {
+	t.Helper()
+	s := &ChannelState{ChannelID: id, Sequence: sequence, Paid: paid}
+	var err error
+	if sender != nil {
+		s.RSender, s.SSender, err = ecdsa.Sign(crand.Reader, sender, s.HashChannelState())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if recipient != nil {
+		s.RRecipient, s.SRecipient, err = ecdsa.Sign(crand.Reader, recipient, s.HashChannelState())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+// openChannel - Returns a chain where alice has opened a channel of
+// capacity 10 to bob out of 20 coins, and the ID of the channel
+func openChannel(t *testing.T, alice, bob *ecdsa.PrivateKey, window uint64) (Blockchain, []byte) {
+	t.Helper()
+	treasury := testKey(t)
+	bc := genesisChain(t, transfer(t, treasury, &alice.PublicKey, 20))
+	open := channelTX(t, TXChannelOpen, alice, &bob.PublicKey, 10, ChannelPayload{DisputeWindow: window})
+	b := nextBlock(t, &bc, open)
+	if !bc.AddBlock(&b) {
+		t.Fatal("open rejected")
+	}
+	if balance := bc.CalcAccountBalanceOnBC(&alice.PublicKey, -1); balance != 10 {
+		t.Fatalf("balance after the open = %v, want 10", balance)
+	}
+	return bc, open.HashTransaction()
+}
+
+func TestChannelDispute(t *testing.T) {
+	alice := testKey(t)
+	bob := testKey(t)
+	bc, id := openChannel(t, alice, bob, 3)
+	add := func(tx Transaction) bool {
+		b := nextBlock(t, &bc, tx)
+		return bc.AddBlock(&b)
+	}
+
+	// Alice closes with an old state that paid bob less
+	old := channelState(t, id, 1, 2, alice, nil)
+	latest := channelState(t, id, 2, 6, alice, nil)
+	if !add(channelTX(t, TXChannelClose, alice, &bob.PublicKey, 0, ChannelPayload{ChannelID: id, State: old})) {
+		t.Fatal("close rejected")
+	}
+	if add(channelTX(t, TXChannelSettle, alice, &bob.PublicKey, 0, ChannelPayload{ChannelID: id})) {
+		t.Fatal("settle inside the dispute window accepted")
+	}
+	if add(channelTX(t, TXChannelDispute, bob, &bob.PublicKey, 0, ChannelPayload{ChannelID: id, State: old})) {
+		t.Fatal("dispute without a newer state accepted")
+	}
+	forged := channelState(t, id, 3, 10, bob, nil)
+	if add(channelTX(t, TXChannelDispute, bob, &bob.PublicKey, 0, ChannelPayload{ChannelID: id, State: forged})) {
+		t.Fatal("dispute with a state the sender didn't sign accepted")
+	}
+	if !add(channelTX(t, TXChannelDispute, bob, &bob.PublicKey, 0, ChannelPayload{ChannelID: id, State: latest})) {
+		t.Fatal("dispute rejected")
+	}
+
+	bc = extend(t, bc, 2)
+	if !add(channelTX(t, TXChannelSettle, alice, &bob.PublicKey, 0, ChannelPayload{ChannelID: id})) {
+		t.Fatal("settle after the dispute window rejected")
+	}
+	if add(channelTX(t, TXChannelSettle, alice, &bob.PublicKey, 0, ChannelPayload{ChannelID: id})) {
+		t.Fatal("second settle accepted")
+	}
+
+	if balance := bc.CalcAccountBalanceOnBC(&alice.PublicKey, -1); balance != 14 {
+		t.Fatalf("alice balance = %v, want 14", balance)
+	}
+	if balance := bc.CalcAccountBalanceOnBC(&bob.PublicKey, -1); balance != 6 {
+		t.Fatalf("bob balance = %v, want 6", balance)
+	}
+}
+
+func TestChannelCoopClose(t *testing.T) {
+	alice := testKey(t)
+	bob := testKey(t)
+	stranger := testKey(t)
+	bc, id := openChannel(t, alice, bob, 100)
+
+	invalid := map[string]Transaction{
+		"close by someone else": channelTX(t, TXChannelClose, stranger, &bob.PublicKey, 0,
+			ChannelPayload{ChannelID: id, State: channelState(t, id, 1, 1, alice, nil)}),
+		"close paying more than the capacity": channelTX(t, TXChannelClose, alice, &bob.PublicKey, 0,
+			ChannelPayload{ChannelID: id, State: channelState(t, id, 1, 11, alice, nil)}),
+		"coop close signed by one side": channelTX(t, TXChannelCoopClose, bob, &bob.PublicKey, 0,
+			ChannelPayload{ChannelID: id, State: channelState(t, id, 1, 4, alice, nil)}),
+		"dispute of an open channel": channelTX(t, TXChannelDispute, bob, &bob.PublicKey, 0,
+			ChannelPayload{ChannelID: id, State: channelState(t, id, 1, 4, alice, nil)}),
+		"open without a dispute window": channelTX(t, TXChannelOpen, alice, &bob.PublicKey, 1, ChannelPayload{}),
+	}
+	for name, tx := range invalid {
+		if tx.TransactionTypeIsValid(&bc, nil) {
+			t.Fatalf("%s accepted", name)
+		}
+	}
+
+	coop := channelTX(t, TXChannelCoopClose, bob, &bob.PublicKey, 0,
+		ChannelPayload{ChannelID: id, State: channelState(t, id, 1, 4, alice, bob)})
+	b := nextBlock(t, &bc, coop)
+	if !bc.AddBlock(&b) {
+		t.Fatal("coop close rejected")
+	}
+	if balance := bc.CalcAccountBalanceOnBC(&alice.PublicKey, -1); balance != 16 {
+		t.Fatalf("alice balance = %v, want 16", balance)
+	}
+	if balance := bc.CalcAccountBalanceOnBC(&bob.PublicKey, -1); balance != 4 {
+		t.Fatalf("bob balance = %v, want 4", balance)
+	}
+}
+
+func TestChannelStateHashSeparatesFields(t *testing.T) {
+	// Each pair writes out to the same digits when the fields are
+	// just joined
+	pairs := [][2]ChannelState{
+		{{ChannelID: []byte("c"), Sequence: 12, Paid: 3.5}, {ChannelID: []byte("c"), Sequence: 1, Paid: 23.5}},
+		{{ChannelID: []byte("c1"), Sequence: 2, Paid: 1}, {ChannelID: []byte("c"), Sequence: 12, Paid: 1}},
+	}
+	for _, pair := range pairs {
+		if string(pair[0].HashChannelState()) == string(pair[1].HashChannelState()) {
+			t.Fatalf("states %+v and %+v hash the same", pair[0], pair[1])
+		}
+	}
+
+	// So a signature over one of them doesn't carry over
+	alice := testKey(t)
+	c := &Channel{ID: []byte("c"), XSender: alice.X, YSender: alice.Y, Capacity: 100}
+	signed := channelState(t, c.ID, 12, 3.5, alice, nil)
+	forged := &ChannelState{ChannelID: c.ID, Sequence: 1, Paid: 23.5, RSender: signed.RSender, SSender: signed.SSender}
+	if c.StateIsValid(forged) {
+		t.Fatal("signature of one state accepted for another")
+	}
+}
+
+func TestChannelTransactionsCountPending(t *testing.T) {
+	alice := testKey(t)
+	bob := testKey(t)
+	bc, id := openChannel(t, alice, bob, 1)
+
+	// A channel only closes once, whether or not the closes are in
+	// the same block
+	aliceClose := channelTX(t, TXChannelClose, alice, &bob.PublicKey, 0,
+		ChannelPayload{ChannelID: id, State: channelState(t, id, 1, 2, alice, nil)})
+	bobClose := channelTX(t, TXChannelClose, bob, &bob.PublicKey, 0,
+		ChannelPayload{ChannelID: id, State: channelState(t, id, 2, 6, alice, nil)})
+	b := nextBlock(t, &bc, aliceClose, bobClose)
+	if bc.AddBlock(&b) {
+		t.Fatal("block closing a channel twice accepted")
+	}
+	coop := func(paid float64) Transaction {
+		return channelTX(t, TXChannelCoopClose, bob, &bob.PublicKey, 0,
+			ChannelPayload{ChannelID: id, State: channelState(t, id, 1, paid, alice, bob)})
+	}
+	b = nextBlock(t, &bc, coop(4), coop(5))
+	if bc.AddBlock(&b) {
+		t.Fatal("block paying out a channel twice accepted")
+	}
+
+	b = nextBlock(t, &bc, aliceClose)
+	if !bc.AddBlock(&b) {
+		t.Fatal("close rejected")
+	}
+	aliceSettle := channelTX(t, TXChannelSettle, alice, &bob.PublicKey, 0, ChannelPayload{ChannelID: id})
+	bobSettle := channelTX(t, TXChannelSettle, bob, &bob.PublicKey, 0, ChannelPayload{ChannelID: id})
+	b = nextBlock(t, &bc, aliceSettle, bobSettle)
+	if bc.AddBlock(&b) {
+		t.Fatal("block settling a channel twice accepted")
+	}
+	pool := MakeTXPool()
+	if err := pool.AddTransaction(&bc, aliceSettle); err != nil {
+		t.Fatal(err)
+	}
+	if pool.AddTransaction(&bc, bobSettle) == nil {
+		t.Fatal("pool settling a channel twice accepted")
+	}
+
+	// A channel opened earlier in the block can be closed in it
+	open := channelTX(t, TXChannelOpen, alice, &bob.PublicKey, 5, ChannelPayload{DisputeWindow: 1})
+	openID := open.HashTransaction()
+	closeNew := channelTX(t, TXChannelClose, alice, &bob.PublicKey, 0,
+		ChannelPayload{ChannelID: openID, State: channelState(t, openID, 1, 1, alice, nil)})
+	b = nextBlock(t, &bc, aliceSettle, open, closeNew)
+	if !bc.AddBlock(&b) {
+		t.Fatal("block settling one channel and opening and closing another rejected")
+	}
+	if balance := bc.CalcAccountBalanceOnBC(&bob.PublicKey, -1); balance != 2 {
+		t.Fatalf("bob balance = %v, want 2", balance)
+	}
+}
@@ -0,0 +1,126 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+const (
+	// compressedP384Len - Length of a compressed P-384 key (in bytes)
+	compressedP384Len = 49
+
+	// compressedSecp256k1Len - Length of a compressed secp256k1
+	// key (in bytes)
+	compressedSecp256k1Len = 33
+)
+
+// CompressPublicKey - Encodes a P-384 or secp256k1 public key as a SEC1
+// compressed point: the parity of Y followed by X. The length tells
+// the two curves apart. Returns nil if the key isn't a point on
+// either curve, like the account IDs of multisig, script and
+// contract accounts or Ed25519 keys
+func CompressPublicKey(pubKey *ecdsa.PublicKey) []byte {
+	if pubKey.X == nil || pubKey.Y == nil {
+		return nil
+	}
+	for _, curve := range []elliptic.Curve{elliptic.P384(), secp256k1.S256()} {
+		if pointIsOnCurve(curve, pubKey.X, pubKey.Y) {
+			return elliptic.MarshalCompressed(curve, pubKey.X, pubKey.Y)
+		}
+	}
+	return nil
+}
+
+// pointIsOnCurve - Returns true if (x, y) is a point of the curve.
+// Coordinates past the field size get turned down even if they'd
+// land on the curve once reduced, so decompressing always gives
+// back the same numbers
+func pointIsOnCurve(curve elliptic.Curve, x *big.Int, y *big.Int) bool {
+	p := curve.Params().P
+	if x.Sign() < 0 || y.Sign() < 0 || x.Cmp(p) >= 0 || y.Cmp(p) >= 0 {
+		return false
+	}
+	return curve.IsOnCurve(x, y)
+}
+
+// DecompressPublicKey - Decodes a key encoded by CompressPublicKey.
+// Fails if the point isn't on the curve
+func DecompressPublicKey(data []byte) (*ecdsa.PublicKey, error) {
+	switch len(data) {
+	case compressedP384Len:
+		x, y := elliptic.UnmarshalCompressed(elliptic.P384(), data)
+		if x == nil {
+			return nil, errors.New("DecompressPublicKey: point isn't on P-384")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P384(), X: x, Y: y}, nil
+	case compressedSecp256k1Len:
+		pubKey, err := secp256k1.ParsePubKey(data)
+		if err != nil {
+			return nil, errors.New("DecompressPublicKey: point isn't on secp256k1")
+		}
+		return pubKey.ToECDSA(), nil
+	}
+	return nil, errors.New("DecompressPublicKey: unknown key length")
+}
+
+// transactionFields - Transaction without its JSON methods
+type transactionFields Transaction
+
+// transactionJSON - How a transaction gets serialized. Input and
+// output keys that are curve points go in InputKey and OutputKey,
+// compressed, instead of in the coordinates
+type transactionJSON struct {
+	transactionFields
+	InputKey  []byte `json:"InputKey,omitempty"`
+	OutputKey []byte `json:"OutputKey,omitempty"`
+}
+
+// MarshalJSON - Serializes the transaction with its keys compressed
+// wherever they can be
+func (t Transaction) MarshalJSON() ([]byte, error) {
+	v := transactionJSON{transactionFields: transactionFields(t)}
+	if key := CompressPublicKey(&ecdsa.PublicKey{X: t.XInput, Y: t.YInput}); key != nil {
+		v.InputKey = key
+		v.XInput = nil
+		v.YInput = nil
+	}
+	if key := CompressPublicKey(&ecdsa.PublicKey{X: t.XOutput, Y: t.YOutput}); key != nil {
+		v.OutputKey = key
+		v.XOutput = nil
+		v.YOutput = nil
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON - Deserializes a transaction, decompressing its keys.
+// Fails if a compressed key isn't on its curve
+func (t *Transaction) UnmarshalJSON(data []byte) error {
+	var v transactionJSON
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	if v.InputKey != nil {
+		pubKey, err := DecompressPublicKey(v.InputKey)
+		if err != nil {
+			return err
+		}
+		v.XInput = pubKey.X
+		v.YInput = pubKey.Y
+	}
+	if v.OutputKey != nil {
+		pubKey, err := DecompressPublicKey(v.OutputKey)
+		if err != nil {
+			return err
+		}
+		v.XOutput = pubKey.X
+		v.YOutput = pubKey.Y
+	}
+	*t = Transaction(v.transactionFields)
+	return nil
+}
@@ -0,0 +1,115 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+func TestCompressPublicKey(t *testing.T) {
+	// The generator of secp256k1
+	g := secp256k1.S256().Params()
+	compressed := CompressPublicKey(&ecdsa.PublicKey{X: g.Gx, Y: g.Gy})
+	want := "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	if hex.EncodeToString(compressed) != want {
+		t.Fatalf("compressed generator = %x", compressed)
+	}
+
+	p384Key := testKey(t)
+	secpKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := map[int]*ecdsa.PublicKey{
+		compressedP384Len:      &p384Key.PublicKey,
+		compressedSecp256k1Len: &secpKey.ToECDSA().PublicKey,
+	}
+	for length, pubKey := range keys {
+		compressed := CompressPublicKey(pubKey)
+		if len(compressed) != length {
+			t.Fatalf("compressed key is %d bytes, want %d", len(compressed), length)
+		}
+		if prefix := byte(2 + pubKey.Y.Bit(0)); compressed[0] != prefix {
+			t.Fatalf("prefix = %x, want %x", compressed[0], prefix)
+		}
+		decompressed, err := DecompressPublicKey(compressed)
+		if err != nil || decompressed.X.Cmp(pubKey.X) != 0 || decompressed.Y.Cmp(pubKey.Y) != 0 {
+			t.Fatalf("DecompressPublicKey = %v, %v", decompressed, err)
+		}
+	}
+
+	// Account IDs that aren't curve points stay uncompressed
+	pubKey, _, _ := ed25519.GenerateKey(crand.Reader)
+	notPoints := []*ecdsa.PublicKey{
+		Ed25519AccountID(pubKey),
+		{X: big.NewInt(1), Y: big.NewInt(2)},
+		{X: new(big.Int).Add(p384Key.X, elliptic.P384().Params().P), Y: p384Key.Y},
+		{},
+	}
+	for i, pubKey := range notPoints {
+		if CompressPublicKey(pubKey) != nil {
+			t.Fatalf("account %d compressed", i)
+		}
+	}
+
+	bad := CompressPublicKey(&p384Key.PublicKey)
+	bad[0] = 4
+	for i, data := range [][]byte{bad, make([]byte, compressedSecp256k1Len), make([]byte, 10)} {
+		if _, err := DecompressPublicKey(data); err == nil {
+			t.Fatalf("bad key %d decompressed", i)
+		}
+	}
+}
+
+func TestTransactionJSONCompressesKeys(t *testing.T) {
+	treasury := testKey(t)
+	alice := testKey(t)
+	tx := transfer(t, treasury, &alice.PublicKey, 1)
+	data, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"InputKey"`) || !strings.Contains(string(data), `"XInput":null`) {
+		t.Fatalf("keys not compressed: %s", data)
+	}
+
+	var decoded Transaction
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.TransactionSignatureIsValid() || string(decoded.HashTransaction()) != string(tx.HashTransaction()) {
+		t.Fatal("transaction changed by a JSON round trip")
+	}
+
+	// Accounts that aren't points keep their coordinates
+	pubKey, _, _ := ed25519.GenerateKey(crand.Reader)
+	account := Ed25519AccountID(pubKey)
+	tx.XOutput, tx.YOutput = account.X, account.Y
+	data, _ = json.Marshal(tx)
+	decoded = Transaction{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.XOutput.Cmp(account.X) != 0 || decoded.YOutput.Sign() != 0 {
+		t.Fatal("Ed25519 output changed by a JSON round trip")
+	}
+
+	// A compressed key that isn't on its curve
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	fields["InputKey"] = make([]byte, compressedP384Len)
+	forged, _ := json.Marshal(fields)
+	if json.Unmarshal(forged, &decoded) == nil {
+		t.Fatal("transaction with a key off the curve decoded")
+	}
+}
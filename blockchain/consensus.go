@@ -0,0 +1,157 @@
+package blockchain
+
+import (
+	"errors"
+	"math/big"
+)
+
+const (
+	// DefaultDifficulty - The difficulty the first block of
+	// a chain gets when no previous block exists to copy it from
+	DefaultDifficulty = 1
+)
+
+// ConsensusEngine - The set of rules that decides how a block
+// gets sealed, whether a sealed block is valid, and which of two
+// competing chains wins. The Blockchain delegates to whichever
+// engine is set through SetConsensusEngine, so swapping consensus
+// doesn't need any changes to the rest of the package
+type ConsensusEngine interface {
+	// Seal - Does the work needed to make the block acceptable
+	// on top of bc (find a nonce, sign it, etc.)
+	Seal(bc *Blockchain, b *Block) error
+
+	// VerifySeal - Returns true if the seal of the block is
+	// valid on top of bc
+	VerifySeal(bc *Blockchain, b *Block) bool
+
+	// CalcDifficulty - Returns the difficulty the next block
+	// on top of bc must have
+	CalcDifficulty(bc *Blockchain) uint32
+
+	// BlockWeight - Returns how much a block counts towards
+	// the total weight of its chain
+	BlockWeight(b *Block) *big.Int
+
+	// ForkChoice - Returns true if the candidate chain should
+	// replace the current chain
+	ForkChoice(current Blockchain, candidate Blockchain) bool
+}
+
+// consensus - The engine the blockchain currently delegates to
+var consensus ConsensusEngine = &ProofOfWork{}
+
+// SetConsensusEngine - Sets the consensus engine used by every
+// blockchain in this process. Call this before adding any blocks
+func SetConsensusEngine(e ConsensusEngine) {
+	consensus = e
+}
+
+// CurrentConsensusEngine - Returns the consensus engine in use
+func CurrentConsensusEngine() ConsensusEngine {
+	return consensus
+}
+
+// ChainWeight - Returns the sum of the weights of every block
+// in the chain, as computed by the engine
+func ChainWeight(e ConsensusEngine, bc Blockchain) *big.Int {
+	total := new(big.Int)
+	for i := range bc {
+		total.Add(total, e.BlockWeight(&bc[i]))
+	}
+	return total
+}
+
+/************************************
+ * Proof of work
+************************************/
+
+// ProofOfWork - The leading-zeros proof of work engine. A block
+// is valid if its hash starts with Difficulty zero bytes, and
+// the chain with the most accumulated work wins
+type ProofOfWork struct{}
+
+// Seal - Mines the block
+func (pow *ProofOfWork) Seal(bc *Blockchain, b *Block) error {
+	b.MineBlock()
+	return nil
+}
+
+// VerifySeal - Checks the hash of the block against its difficulty
+func (pow *ProofOfWork) VerifySeal(bc *Blockchain, b *Block) bool {
+	return b.BlockHashIsValid()
+}
+
+// CalcDifficulty - The difficulty doesn't retarget yet, so the
+// next block just keeps the difficulty of the last one
+func (pow *ProofOfWork) CalcDifficulty(bc *Blockchain) uint32 {
+	if len(*bc) == 0 {
+		return DefaultDifficulty
+	}
+	return (*bc)[len(*bc)-1].Difficulty
+}
+
+// BlockWeight - Every leading zero byte makes a block 256 times
+// harder to find, so the expected work is 2^(8 * Difficulty)
+func (pow *ProofOfWork) BlockWeight(b *Block) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(b.Difficulty)*8)
+}
+
+// ForkChoice - The heaviest chain wins
+func (pow *ProofOfWork) ForkChoice(current Blockchain, candidate Blockchain) bool {
+	return ChainWeight(pow, candidate).Cmp(ChainWeight(pow, current)) > 0
+}
+
+/************************************
+ * Blockchain helpers that go through
+ * the consensus engine
+************************************/
+
+// SealBlock - Fills in the version, index, previous hash, difficulty
+// and state root of a block so that it extends the blockchain, then
+// seals it using the current consensus engine
+func (bc *Blockchain) SealBlock(b *Block) error {
+	b.Version = bc.CalcBlockVersion()
+	b.Index = uint64(len(*bc))
+	if len(*bc) > 0 {
+		b.PrevHash = (*bc)[len(*bc)-1].Hash
+	}
+	b.Difficulty = consensus.CalcDifficulty(bc)
+	b.StateRoot = bc.CalcStateRoot(b)
+	return consensus.Seal(bc, b)
+}
+
+// ReplaceChain - Replaces the blockchain with the candidate chain if
+// the consensus engine prefers it, it starts from the same genesis
+// block, it doesn't reorganize below the last final block, and every
+// block of the candidate above the fork point is valid on top of the
+// blocks below it. The blocks below the fork point are kept from
+// the current chain. Returns true if the chain was replaced
+func (bc *Blockchain) ReplaceChain(candidate Blockchain) (bool, error) {
+	if !consensus.ForkChoice(*bc, candidate) {
+		return false, nil
+	}
+	if finality != nil && !finality.ChainIsCompatible(candidate) {
+		return false, errors.New("ReplaceChain: candidate doesn't contain the last final block")
+	}
+
+	old := bc.Snapshot()
+	fork := forkPoint(old, candidate)
+	if len(old) > 0 && fork == 0 {
+		return false, errors.New("ReplaceChain: candidate has a different genesis block")
+	}
+	chain := append(append(Blockchain{}, old[:fork]...), candidate[fork:]...)
+	for height := uint64(fork); height < uint64(len(chain)); height++ {
+		if reason := chain.verifyBlock(height); reason != "" {
+			return false, &VerifyError{Height: height, Reason: reason}
+		}
+	}
+	candidate = chain
+
+	chainMux.Lock()
+	old = *bc
+	*bc = candidate
+	chainMux.Unlock()
+	reorganize(old, candidate)
+	return true, nil
+}
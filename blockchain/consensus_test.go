@@ -0,0 +1,49 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReplaceChainValidatesCandidate(t *testing.T) {
+	treasury := testKey(t)
+	alice := testKey(t)
+	bob := testKey(t)
+	bc := genesisChain(t, transfer(t, treasury, &alice.PublicKey, 10))
+	tip := nextBlock(t, &bc)
+	if !bc.AddBlock(&tip) {
+		t.Fatal("empty block rejected")
+	}
+
+	// A longer fork whose first block spends coins bob never had
+	forged := Blockchain{bc[0]}
+	forged = append(forged, nextBlock(t, &forged, transfer(t, bob, &alice.PublicKey, 50)))
+	forged = append(forged, nextBlock(t, &forged))
+	forged = append(forged, nextBlock(t, &forged))
+	replaced, err := bc.ReplaceChain(forged)
+	var verr *VerifyError
+	if replaced || !errors.As(err, &verr) || verr.Height != 1 {
+		t.Fatalf("ReplaceChain = %v, %v, want a failure at height 1", replaced, err)
+	}
+
+	// The same fork with funded spends
+	fork := Blockchain{bc[0]}
+	fork = append(fork, nextBlock(t, &fork, transfer(t, alice, &bob.PublicKey, 5)))
+	fork = append(fork, nextBlock(t, &fork))
+	fork = append(fork, nextBlock(t, &fork))
+	if replaced, err := bc.ReplaceChain(fork); !replaced || err != nil {
+		t.Fatalf("ReplaceChain = %v, %v", replaced, err)
+	}
+	if len(bc) != 4 || bc.CalcAccountBalanceOnBC(&bob.PublicKey, -1) != 5 {
+		t.Fatal("chain not replaced by the fork")
+	}
+
+	// A heavier chain from another genesis block
+	other := genesisChain(t)
+	for len(other) < 6 {
+		other = append(other, nextBlock(t, &other))
+	}
+	if replaced, err := bc.ReplaceChain(other); replaced || err == nil {
+		t.Fatal("chain with a different genesis block accepted")
+	}
+}
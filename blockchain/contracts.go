@@ -0,0 +1,203 @@
+package blockchain
+
+import (
+	"Blockchain/compute"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"errors"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+const (
+	// ValueUnits - How many VM value units one coin is worth.
+	// CALLVALUE sees Amount in these units
+	ValueUnits = 100000000
+)
+
+// ContractState - The code and storage of every deployed
+// contract, keyed by the account ID of the contract
+type ContractState struct {
+	Contracts map[string]*compute.Contract `json:"Contracts"`
+}
+
+// MakeContractState - ContractState constructor
+func MakeContractState() *ContractState {
+	return &ContractState{Contracts: make(map[string]*compute.Contract)}
+}
+
+// accountKey - Turns an account into a map key
+func accountKey(x *big.Int, y *big.Int) string {
+	return x.String() + "," + y.String()
+}
+
+// ContractAccountID - Derives the account ID of the contract a
+// TXDeploy transaction deploys from its sender, timestamp and code
+func ContractAccountID(t *Transaction) *ecdsa.PublicKey {
+	var buff hashBuffer
+	buff.putBig(t.XInput)
+	buff.putBig(t.YInput)
+	buff.putUint(t.Timestamp)
+	buff.putBytes(t.Data)
+	return deriveAccountID("contract", buff)
+}
+
+// callerID - The 32 byte identifier of the sender that CALLER pushes
+func callerID(t *Transaction) []byte {
+	hash := sha256.Sum256([]byte(accountKey(t.XInput, t.YInput)))
+	return hash[:]
+}
+
+// ApplyTransaction - Deploys or calls a contract. A call that fails
+// leaves the storage of the contract as it was. Transactions that
+// aren't TXDeploy or TXCall don't touch the state
+func (s *ContractState) ApplyTransaction(t *Transaction) error {
+	switch t.Type {
+	case TXDeploy:
+		key := accountKey(t.XOutput, t.YOutput)
+		if _, exists := s.Contracts[key]; exists {
+			return errors.New("ApplyTransaction: contract already deployed")
+		}
+		s.Contracts[key] = compute.MakeContract(t.Data)
+	case TXCall:
+		contract, exists := s.Contracts[accountKey(t.XOutput, t.YOutput)]
+		if !exists {
+			return errors.New("ApplyTransaction: no contract at the output")
+		}
+		ctx := compute.CallContext{
+			Caller:   callerID(t),
+			Value:    uint64(math.Round(t.Amount * ValueUnits)),
+			Input:    t.Data,
+			GasLimit: t.GasLimit,
+		}
+		_, _, err := contract.Execute(ctx)
+		return err
+	}
+	return nil
+}
+
+// copy - Returns a copy of the state that transactions can be
+// applied to without touching the original
+func (s *ContractState) copy() *ContractState {
+	c := MakeContractState()
+	for id, contract := range s.Contracts {
+		storage := make(map[string][]byte, len(contract.Storage))
+		for k, v := range contract.Storage {
+			storage[k] = v
+		}
+		c.Contracts[id] = &compute.Contract{Code: contract.Code, Storage: storage}
+	}
+	return c
+}
+
+// StateRoot - Returns a SHA 256 hash committing to the code and
+// storage of every contract. Returns nil if no contract exists,
+// so blocks from before any deployment don't commit to anything.
+// Every field is length prefixed, so two different states can't
+// be written the same way
+func (s *ContractState) StateRoot() []byte {
+	if len(s.Contracts) == 0 {
+		return nil
+	}
+
+	var ids []string
+	for id := range s.Contracts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var buff hashBuffer
+	for _, id := range ids {
+		contract := s.Contracts[id]
+		buff.putBytes([]byte(id))
+		codeHash := sha256.Sum256(contract.Code)
+		buff.putBytes(codeHash[:])
+
+		var keys []string
+		for k := range contract.Storage {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buff.putUint(uint64(len(keys)))
+		for _, k := range keys {
+			buff.putBytes([]byte(k))
+			buff.putBytes(contract.Storage[k])
+		}
+	}
+	hash := sha256.Sum256(buff)
+	return hash[:]
+}
+
+// CalcContractStateOnBC - Replays every contract transaction of the
+// blockchain. The index parameter works the same way as in
+// CalcAccountBalanceOnBC
+func (bc *Blockchain) CalcContractStateOnBC(index int64) *ContractState {
+	state := MakeContractState()
+	limit := bc.blockLimit(index)
+	for i := 0; i < limit; i++ {
+		for j := range (*bc)[i].TXs {
+			state.ApplyTransaction(&(*bc)[i].TXs[j])
+		}
+	}
+	return state
+}
+
+// runningState - The contract state after the block with hash
+// runningHash, so that checking or sealing the next block only has
+// to apply the transactions of the blocks since
+var runningState = MakeContractState()
+var runningHash []byte
+var runningStateMux sync.Mutex
+
+// contractStateAtTip - Returns a copy of the contract state after the
+// last block of the blockchain. The running state is moved forward
+// when it's for the block below the last one, and only if the result
+// matches the state root the last block commits to. Any other chain
+// gets replayed from the start
+func (bc *Blockchain) contractStateAtTip() *ContractState {
+	n := len(*bc)
+	if n == 0 {
+		return MakeContractState()
+	}
+	tip := &(*bc)[n-1]
+	if len(tip.Hash) == 0 {
+		return bc.CalcContractStateOnBC(-1)
+	}
+
+	runningStateMux.Lock()
+	defer runningStateMux.Unlock()
+	if bytes.Equal(runningHash, tip.Hash) && bytes.Equal(runningState.StateRoot(), tip.StateRoot) {
+		return runningState.copy()
+	}
+
+	var state *ContractState
+	if n > 1 && len(runningHash) > 0 && bytes.Equal(runningHash, (*bc)[n-2].Hash) &&
+		bytes.Equal(runningState.StateRoot(), (*bc)[n-2].StateRoot) {
+		state = runningState.copy()
+		for i := range tip.TXs {
+			state.ApplyTransaction(&tip.TXs[i])
+		}
+		if !bytes.Equal(state.StateRoot(), tip.StateRoot) {
+			state = nil
+		}
+	}
+	if state == nil {
+		state = bc.CalcContractStateOnBC(-1)
+	}
+	runningState = state
+	runningHash = append([]byte(nil), tip.Hash...)
+	return state.copy()
+}
+
+// CalcStateRoot - Returns the state root the block has to commit to
+// if it's added on top of the blockchain
+func (bc *Blockchain) CalcStateRoot(b *Block) []byte {
+	state := bc.contractStateAtTip()
+	for i := range b.TXs {
+		state.ApplyTransaction(&b.TXs[i])
+	}
+	return state.StateRoot()
+}
@@ -0,0 +1,95 @@
+package blockchain
+
+import (
+	"Blockchain/compute"
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func TestStateRootSeparatesFields(t *testing.T) {
+	// The same bytes split differently between a storage key and its
+	// value are a different state
+	a := MakeContractState()
+	a.Contracts["c"] = &compute.Contract{Storage: map[string][]byte{"ab": []byte("c")}}
+	b := MakeContractState()
+	b.Contracts["c"] = &compute.Contract{Storage: map[string][]byte{"a": []byte("bc")}}
+	if bytes.Equal(a.StateRoot(), b.StateRoot()) {
+		t.Fatal("different storage maps have the same state root")
+	}
+
+	// So are the same bytes split between the sender coordinates
+	x := Transaction{Type: TXDeploy, XInput: big.NewInt(12), YInput: big.NewInt(3)}
+	y := Transaction{Type: TXDeploy, XInput: big.NewInt(1), YInput: big.NewInt(23)}
+	idX, idY := ContractAccountID(&x), ContractAccountID(&y)
+	if idX.X.Cmp(idY.X) == 0 && idX.Y.Cmp(idY.Y) == 0 {
+		t.Fatal("different senders deploy to the same account")
+	}
+}
+
+// counterCall - Returns a call to the contract of the deployment,
+// which adds one to its first storage slot
+func counterCall(t *testing.T, deploy *Transaction, timestamp uint64) Transaction {
+	t.Helper()
+	id := ContractAccountID(deploy)
+	return Transaction{
+		Type:      TXCall,
+		XInput:    deploy.XInput,
+		YInput:    deploy.YInput,
+		XOutput:   id.X,
+		YOutput:   id.Y,
+		GasLimit:  1000,
+		Timestamp: timestamp,
+	}
+}
+
+func TestCalcStateRootMatchesReplay(t *testing.T) {
+	SetChainParams(DefaultChainParams())
+	defer SetChainParams(DefaultChainParams())
+	key := testKey(t)
+	code := []byte{
+		compute.OpPush1, 1, compute.OpPush1, 0, compute.OpSLoad, compute.OpAdd,
+		compute.OpPush1, 0, compute.OpSStore,
+	}
+	deploy := Transaction{Type: TXDeploy, XInput: key.X, YInput: key.Y, Data: code}
+	id := ContractAccountID(&deploy)
+	deploy.XOutput, deploy.YOutput = id.X, id.Y
+
+	// replayRoot - The state root of a block on top of the chain,
+	// replaying everything from the start
+	replayRoot := func(bc *Blockchain, b *Block) []byte {
+		state := bc.CalcContractStateOnBC(-1)
+		for i := range b.TXs {
+			state.ApplyTransaction(&b.TXs[i])
+		}
+		return state.StateRoot()
+	}
+	check := func(bc *Blockchain, b *Block) {
+		t.Helper()
+		if want := replayRoot(bc, b); !bytes.Equal(bc.CalcStateRoot(b), want) || !bytes.Equal(b.StateRoot, want) {
+			t.Fatalf("state root at height %d doesn't match a replay", len(*bc))
+		}
+	}
+
+	bc := genesisChain(t, deploy)
+	for i := uint64(1); i <= 3; i++ {
+		b := nextBlock(t, &bc, counterCall(t, &deploy, i))
+		check(&bc, &b)
+		bc = append(bc, b)
+	}
+
+	// A fork off the second block doesn't pick up the state of the
+	// chain that was checked last, and neither does going back to it
+	fork := append(Blockchain{}, bc[:2]...)
+	b := nextBlock(t, &fork, counterCall(t, &deploy, 10), counterCall(t, &deploy, 11))
+	check(&fork, &b)
+	fork = append(fork, b)
+	b = nextBlock(t, &fork)
+	check(&fork, &b)
+
+	b = nextBlock(t, &bc, counterCall(t, &deploy, 4))
+	check(&bc, &b)
+	if slot := bc.CalcContractStateOnBC(-1).Contracts[accountKey(id.X, id.Y)].Storage; len(slot) != 1 {
+		t.Fatalf("storage = %v, want one slot", slot)
+	}
+}
@@ -0,0 +1,158 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+const (
+	// EventBlockConnected - A block became part of the chain
+	EventBlockConnected = 0
+
+	// EventBlockDisconnected - A reorganization took a block back
+	// out of the chain
+	EventBlockDisconnected = 1
+
+	// EventTXAccepted - A transaction was accepted to a pool
+	EventTXAccepted = 2
+
+	// EventTXEvicted - A transaction left a pool without
+	// making it into a block
+	EventTXEvicted = 3
+
+	// MaxDroppedEvents - How many events a subscriber can miss
+	// because its channel was full before it gets unsubscribed
+	MaxDroppedEvents = 1024
+)
+
+// ChainEvent - Something that happened to the chain or a pool.
+// Block is set for block events, TX for transaction events
+type ChainEvent struct {
+	Type   int          `json:"Type"`
+	Height uint64       `json:"Height"`
+	Block  *Block       `json:"Block"`
+	TX     *Transaction `json:"TX"`
+}
+
+// Subscription - A subscriber to chain events. Events never wait for
+// the subscriber: if C is full, the event is dropped, and once more
+// than MaxDroppedEvents were dropped the subscription is cancelled
+// and C gets closed
+type Subscription struct {
+	C       <-chan ChainEvent
+	ch      chan ChainEvent
+	dropped int
+	closed  bool
+}
+
+var subscribers []*Subscription
+var subscriberMux sync.Mutex
+
+// Subscribe - Starts receiving chain events on a channel that can
+// hold buffer events
+func Subscribe(buffer int) *Subscription {
+	ch := make(chan ChainEvent, buffer)
+	s := &Subscription{C: ch, ch: ch}
+
+	subscriberMux.Lock()
+	defer subscriberMux.Unlock()
+	subscribers = append(subscribers, s)
+	return s
+}
+
+// Unsubscribe - Stops receiving chain events and closes C
+func (s *Subscription) Unsubscribe() {
+	subscriberMux.Lock()
+	defer subscriberMux.Unlock()
+	s.cancel()
+}
+
+// Dropped - Returns how many events the subscriber missed
+func (s *Subscription) Dropped() int {
+	subscriberMux.Lock()
+	defer subscriberMux.Unlock()
+	return s.dropped
+}
+
+// cancel - Removes the subscription and closes its channel.
+// Needs subscriberMux held
+func (s *Subscription) cancel() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.ch)
+	for i := range subscribers {
+		if subscribers[i] == s {
+			subscribers = append(subscribers[:i], subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// publish - Sends an event to every subscriber without blocking
+func publish(e ChainEvent) {
+	subscriberMux.Lock()
+	defer subscriberMux.Unlock()
+
+	// Go over a copy, since slow subscribers can get
+	// removed along the way
+	current := make([]*Subscription, len(subscribers))
+	copy(current, subscribers)
+	for _, s := range current {
+		select {
+		case s.ch <- e:
+		default:
+			s.dropped++
+			if s.dropped > MaxDroppedEvents {
+				s.cancel()
+			}
+		}
+	}
+}
+
+// hasSubscribers - Returns true if anyone is subscribed, so events
+// nobody would get don't have to be copied
+func hasSubscribers() bool {
+	subscriberMux.Lock()
+	defer subscriberMux.Unlock()
+	return len(subscribers) > 0
+}
+
+// deepCopy - Copies src into dst through JSON, so the copy shares
+// no slices or big.Ints with the original
+func deepCopy(dst interface{}, src interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// publishBlock - Publishes a block event with a copy of the block
+func publishBlock(eventType int, b *Block, height uint64) {
+	if !hasSubscribers() {
+		return
+	}
+	var blockCopy Block
+	if err := deepCopy(&blockCopy, b); err != nil {
+		log.Printf("publishBlock: %v", err)
+		return
+	}
+	publish(ChainEvent{Type: eventType, Height: height, Block: &blockCopy})
+}
+
+// publishTX - Publishes a transaction event with a copy of
+// the transaction
+func publishTX(eventType int, t *Transaction) {
+	if !hasSubscribers() {
+		return
+	}
+	var txCopy Transaction
+	if err := deepCopy(&txCopy, t); err != nil {
+		log.Printf("publishTX: %v", err)
+		return
+	}
+	publish(ChainEvent{Type: eventType, TX: &txCopy})
+}
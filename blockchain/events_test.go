@@ -0,0 +1,119 @@
+package blockchain
+
+import (
+	"bytes"
+	"testing"
+)
+
+// nextEvent - Returns the next event of the subscription, failing
+// the test if there isn't one waiting
+func nextEvent(t *testing.T, s *Subscription) ChainEvent {
+	t.Helper()
+	select {
+	case e, ok := <-s.C:
+		if !ok {
+			t.Fatal("subscription closed")
+		}
+		return e
+	default:
+		t.Fatal("no event")
+	}
+	return ChainEvent{}
+}
+
+func TestChainEvents(t *testing.T) {
+	treasury := testKey(t)
+	alice := testKey(t)
+	bob := testKey(t)
+	bc := genesisChain(t, transfer(t, treasury, &alice.PublicKey, 10))
+
+	s := Subscribe(16)
+	defer s.Unsubscribe()
+
+	pool := MakeTXPool()
+	pending := transfer(t, alice, &bob.PublicKey, 10)
+	if err := pool.AddTransaction(&bc, pending); err != nil {
+		t.Fatal(err)
+	}
+	if e := nextEvent(t, s); e.Type != EventTXAccepted || !bytes.Equal(e.TX.HashTransaction(), pending.HashTransaction()) {
+		t.Fatalf("event = %+v, want the accepted transaction", e)
+	}
+
+	// A block spends the coins the pending transaction was going to
+	b := nextBlock(t, &bc, transfer(t, alice, &treasury.PublicKey, 5))
+	if !bc.AddBlock(&b) {
+		t.Fatal("block rejected")
+	}
+	if e := nextEvent(t, s); e.Type != EventBlockConnected || e.Height != 1 || !bytes.Equal(e.Block.Hash, b.Hash) {
+		t.Fatalf("event = %+v, want block 1 connected", e)
+	}
+	pool.EvictInvalid(&bc)
+	if e := nextEvent(t, s); e.Type != EventTXEvicted || !bytes.Equal(e.TX.HashTransaction(), pending.HashTransaction()) {
+		t.Fatalf("event = %+v, want the evicted transaction", e)
+	}
+
+	// A longer fork disconnects block 1 and connects its own blocks
+	fork := extend(t, bc[:1], 2)
+	if replaced, err := bc.ReplaceChain(fork); !replaced || err != nil {
+		t.Fatalf("ReplaceChain = %v, %v", replaced, err)
+	}
+	if e := nextEvent(t, s); e.Type != EventBlockDisconnected || !bytes.Equal(e.Block.Hash, b.Hash) {
+		t.Fatalf("event = %+v, want block 1 disconnected", e)
+	}
+	for height := uint64(1); height <= 2; height++ {
+		if e := nextEvent(t, s); e.Type != EventBlockConnected || e.Height != height {
+			t.Fatalf("event = %+v, want block %d connected", e, height)
+		}
+	}
+
+	// Events carry copies, so subscribers can't change the chain
+	b = nextBlock(t, &bc)
+	bc.AddBlock(&b)
+	nextEvent(t, s).Block.Hash[0] ^= 0xff
+	if !bc.BlockInBlockchainIsValid(3) {
+		t.Fatal("changing an event changed the chain")
+	}
+
+	s.Unsubscribe()
+	if _, ok := <-s.C; ok {
+		t.Fatal("channel open after Unsubscribe")
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	s := Subscribe(1)
+	defer s.Unsubscribe()
+	b := Block{}
+
+	for i := 0; i <= MaxDroppedEvents; i++ {
+		publishBlock(EventBlockConnected, &b, 0)
+	}
+	if s.Dropped() != MaxDroppedEvents {
+		t.Fatalf("dropped %d events, want %d", s.Dropped(), MaxDroppedEvents)
+	}
+	publishBlock(EventBlockConnected, &b, 0)
+
+	// The one event that fit is still there, then the channel closes
+	if _, ok := <-s.C; !ok {
+		t.Fatal("buffered event lost")
+	}
+	if _, ok := <-s.C; ok {
+		t.Fatal("slow subscriber not cancelled")
+	}
+}
+
+func TestPublishWithoutSubscribers(t *testing.T) {
+	treasury := testKey(t)
+	alice := testKey(t)
+	tx := transfer(t, treasury, &alice.PublicKey, 10)
+	bc := genesisChain(t, tx)
+
+	// Nobody gets the events, so nothing gets copied
+	allocs := testing.AllocsPerRun(10, func() {
+		publishBlock(EventBlockConnected, &bc[0], 0)
+		publishTX(EventTXAccepted, &tx)
+	})
+	if allocs != 0 {
+		t.Fatalf("publishing without subscribers allocated %v times", allocs)
+	}
+}
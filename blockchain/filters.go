@@ -0,0 +1,297 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"sort"
+)
+
+const (
+	// FilterP - Number of low bits of every Golomb-Rice coded value
+	FilterP = 19
+
+	// FilterM - The inverse of the false positive rate of a filter
+	FilterM = 784931
+
+	// MaxFiltersPerMessage - Maximum number of block filters a full
+	// node hands out at once
+	MaxFiltersPerMessage = 1000
+)
+
+// BlockFilter - A compact filter of the public keys the
+// transactions of a block touch (the inputs and outputs). A wallet
+// can test its keys against it without telling anyone what they
+// are, and fetch the block only on a match. Header chains the
+// filter to the filter of the block below it
+type BlockFilter struct {
+	BlockHash []byte `json:"BlockHash"`
+	Height    uint64 `json:"Height"`
+	N         uint32 `json:"N"`
+	Data      []byte `json:"Data"`
+	Header    []byte `json:"Header"`
+}
+
+/************************************
+ * Golomb-coded sets
+************************************/
+
+// bitWriter - Writes a stream of bits, most significant bit first
+type bitWriter struct {
+	data []byte
+	n    uint
+}
+
+// writeBit - Appends a single bit
+func (w *bitWriter) writeBit(bit bool) {
+	if w.n%8 == 0 {
+		w.data = append(w.data, 0)
+	}
+	if bit {
+		w.data[len(w.data)-1] |= 0x80 >> (w.n % 8)
+	}
+	w.n++
+}
+
+// writeBits - Appends the low count bits of v
+func (w *bitWriter) writeBits(v uint64, count uint) {
+	for i := count; i > 0; i-- {
+		w.writeBit(v&(1<<(i-1)) != 0)
+	}
+}
+
+// bitReader - Reads a stream of bits written by bitWriter
+type bitReader struct {
+	data []byte
+	n    uint
+}
+
+// readBit - Reads a single bit. Returns false for ok past the end
+func (r *bitReader) readBit() (bit bool, ok bool) {
+	if r.n/8 >= uint(len(r.data)) {
+		return false, false
+	}
+	bit = r.data[r.n/8]&(0x80>>(r.n%8)) != 0
+	r.n++
+	return bit, true
+}
+
+// readBits - Reads count bits into the low bits of a number
+func (r *bitReader) readBits(count uint) (uint64, bool) {
+	var v uint64 = 0
+	for i := uint(0); i < count; i++ {
+		bit, ok := r.readBit()
+		if !ok {
+			return 0, false
+		}
+		v <<= 1
+		if bit {
+			v |= 1
+		}
+	}
+	return v, true
+}
+
+// hashFilterItem - Maps an item evenly onto [0, n * FilterM). The
+// key is taken from the block hash so that every block hashes its
+// items differently
+func hashFilterItem(key []byte, item []byte, n uint32) uint64 {
+	hash := sha256.Sum256(append(append([]byte{}, key...), item...))
+	h := binary.BigEndian.Uint64(hash[:8])
+	hi, _ := bits.Mul64(h, uint64(n)*FilterM)
+	return hi
+}
+
+// filterKey - The key the items of a block filter get hashed with
+func filterKey(blockHash []byte) []byte {
+	if len(blockHash) > 16 {
+		return blockHash[:16]
+	}
+	return blockHash
+}
+
+// buildGCS - Encodes items as a Golomb-coded set: the sorted hashes
+// are stored as Golomb-Rice coded differences
+func buildGCS(key []byte, items [][]byte) []byte {
+	n := uint32(len(items))
+	hashes := make([]uint64, 0, len(items))
+	for _, item := range items {
+		hashes = append(hashes, hashFilterItem(key, item, n))
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+
+	w := &bitWriter{}
+	var last uint64 = 0
+	for _, h := range hashes {
+		delta := h - last
+		for q := delta >> FilterP; q > 0; q-- {
+			w.writeBit(true)
+		}
+		w.writeBit(false)
+		w.writeBits(delta, FilterP)
+		last = h
+	}
+	return w.data
+}
+
+/************************************
+ * Block filters
+************************************/
+
+// FilterItem - Returns what a public key (or any other account ID)
+// looks like inside a block filter: its address hash, so payments
+// to its address match too
+func FilterItem(pubKey *ecdsa.PublicKey) []byte {
+	return AddressHash(pubKey)
+}
+
+// filterItems - Returns every distinct account the transactions
+// of a block touch
+func filterItems(b *Block) [][]byte {
+	seen := make(map[string]bool)
+	var items [][]byte
+	add := func(hash []byte) {
+		if !seen[string(hash)] {
+			seen[string(hash)] = true
+			items = append(items, hash)
+		}
+	}
+	for i := range b.TXs {
+		tx := &b.TXs[i]
+		if tx.XInput != nil && tx.YInput != nil {
+			add(AddressHash(&ecdsa.PublicKey{X: tx.XInput, Y: tx.YInput}))
+		}
+		if tx.Recipient != nil || (tx.XOutput != nil && tx.YOutput != nil) {
+			add(tx.outputAddressHash())
+		}
+	}
+	return items
+}
+
+// MakeBlockFilter - Builds the filter of a block. The header is
+// chained to prevHeader, the header of the filter of the block
+// below (nil for the first block)
+func MakeBlockFilter(b *Block, height uint64, prevHeader []byte) *BlockFilter {
+	items := filterItems(b)
+	f := &BlockFilter{
+		BlockHash: b.Hash,
+		Height:    height,
+		N:         uint32(len(items)),
+		Data:      buildGCS(filterKey(b.Hash), items),
+	}
+	f.Header = f.CalcFilterHeader(prevHeader)
+	return f
+}
+
+// HashFilter - Returns a SHA 256 hash of the contents of the filter
+func (f *BlockFilter) HashFilter() []byte {
+	buff := make([]byte, 4)
+	binary.BigEndian.PutUint32(buff, f.N)
+	hash := sha256.Sum256(append(buff, f.Data...))
+	return hash[:]
+}
+
+// CalcFilterHeader - Returns the header the filter has to have
+// on top of prevHeader
+func (f *BlockFilter) CalcFilterHeader(prevHeader []byte) []byte {
+	hash := sha256.Sum256(append(f.HashFilter(), prevHeader...))
+	return hash[:]
+}
+
+// FilterHeaderIsValid - Checks that the filter chains up to
+// prevHeader, the header of the filter of the block below
+func (f *BlockFilter) FilterHeaderIsValid(prevHeader []byte) bool {
+	return bytes.Compare(f.CalcFilterHeader(prevHeader), f.Header) == 0
+}
+
+// MatchAny - Returns true if any of the items might be in the filter.
+// False positives happen about once every FilterM items, but an
+// item that is in the filter always matches
+func (f *BlockFilter) MatchAny(items [][]byte) bool {
+	if f.N == 0 || len(items) == 0 {
+		return false
+	}
+
+	key := filterKey(f.BlockHash)
+	targets := make([]uint64, 0, len(items))
+	for _, item := range items {
+		targets = append(targets, hashFilterItem(key, item, f.N))
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i] < targets[j] })
+
+	// Walk the set and the sorted targets together
+	r := &bitReader{data: f.Data}
+	var value uint64 = 0
+	t := 0
+	for i := uint32(0); i < f.N; i++ {
+		var q uint64 = 0
+		for {
+			bit, ok := r.readBit()
+			if !ok {
+				return false
+			}
+			if !bit {
+				break
+			}
+			q++
+		}
+		rem, ok := r.readBits(FilterP)
+		if !ok {
+			return false
+		}
+		value += q<<FilterP | rem
+
+		for t < len(targets) && targets[t] < value {
+			t++
+		}
+		if t == len(targets) {
+			return false
+		}
+		if targets[t] == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Match - Returns true if the item might be in the filter
+func (f *BlockFilter) Match(item []byte) bool {
+	return f.MatchAny([][]byte{item})
+}
+
+// BlockFilters - Returns up to max block filters, starting at height
+// from, with their headers. The header chain starts at the first
+// block, so every filter below from gets built too
+// (@TODO-OPTIMIZE)
+func (bc *Blockchain) BlockFilters(from uint64, max int) []BlockFilter {
+	var filters []BlockFilter
+	var prevHeader []byte
+	for i := uint64(0); i < uint64(len(*bc)) && len(filters) < max; i++ {
+		f := MakeBlockFilter(&(*bc)[i], i, prevHeader)
+		prevHeader = f.Header
+		if i >= from {
+			filters = append(filters, *f)
+		}
+	}
+	return filters
+}
+
+// CheckFilter - Checks a filter from a full node against the header
+// chain and the header of the filter below it (nil for the first
+// block). Asking more than one full node and comparing the headers
+// catches a node that leaves transactions out of its filters
+func (hc *HeaderChain) CheckFilter(f *BlockFilter, prevHeader []byte) error {
+	if f.Height >= hc.Height() {
+		return errors.New("CheckFilter: header chain doesn't reach the block yet")
+	}
+	if bytes.Compare(hc.Headers[f.Height].Hash, f.BlockHash) != 0 {
+		return errors.New("CheckFilter: block isn't on the header chain")
+	}
+	if !f.FilterHeaderIsValid(prevHeader) {
+		return errors.New("CheckFilter: filter doesn't chain to the previous filter header")
+	}
+	return nil
+}
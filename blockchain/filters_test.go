@@ -0,0 +1,124 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"testing"
+)
+
+func TestBlockFilterMatch(t *testing.T) {
+	treasury := testKey(t)
+	var members []*ecdsa.PrivateKey
+	var txs []Transaction
+	for i := 0; i < 20; i++ {
+		key := testKey(t)
+		members = append(members, key)
+		txs = append(txs, transfer(t, treasury, &key.PublicKey, 1))
+	}
+	bc := genesisChain(t, txs...)
+	f := MakeBlockFilter(&bc[0], 0, nil)
+	if f.N != 21 {
+		t.Fatalf("filter has %d items, want 21", f.N)
+	}
+
+	if !f.Match(FilterItem(&treasury.PublicKey)) {
+		t.Fatal("input doesn't match")
+	}
+	for i, key := range members {
+		if !f.Match(FilterItem(&key.PublicKey)) {
+			t.Fatalf("output %d doesn't match", i)
+		}
+	}
+
+	// About one in FilterM items is a false positive, so a few
+	// hundred strangers should never match
+	var strangers [][]byte
+	for i := 0; i < 200; i++ {
+		stranger := testKey(t)
+		item := FilterItem(&stranger.PublicKey)
+		if f.Match(item) {
+			t.Fatalf("stranger %d matches", i)
+		}
+		strangers = append(strangers, item)
+	}
+	if f.MatchAny(strangers) {
+		t.Fatal("strangers match together")
+	}
+	if !f.MatchAny(append(strangers, FilterItem(&members[7].PublicKey))) {
+		t.Fatal("member among strangers doesn't match")
+	}
+
+	// Payments to an address match the key behind it
+	alice := testKey(t)
+	toAddress := transfer(t, treasury, &alice.PublicKey, 1)
+	if err := toAddress.PayToAddress(PublicKeyAddress(&alice.PublicKey)); err != nil {
+		t.Fatal(err)
+	}
+	signTX(t, &toAddress, treasury)
+	b := nextBlock(t, &bc, toAddress)
+	if !MakeBlockFilter(&b, 1, f.Header).Match(FilterItem(&alice.PublicKey)) {
+		t.Fatal("payment to an address doesn't match its key")
+	}
+
+	empty := MakeBlockFilter(&Block{Hash: []byte("empty")}, 0, nil)
+	if empty.Match(FilterItem(&treasury.PublicKey)) {
+		t.Fatal("empty filter matches")
+	}
+}
+
+func TestBlockFilterHeaders(t *testing.T) {
+	defer SetChainParams(ActiveChainParams())
+	SetChainParams(DefaultChainParams())
+
+	treasury := testKey(t)
+	alice := testKey(t)
+	bc := genesisChain(t, transfer(t, treasury, &alice.PublicKey, 10))
+	b := nextBlock(t, &bc, transfer(t, alice, &treasury.PublicKey, 1))
+	if !bc.AddBlock(&b) {
+		t.Fatal("block rejected")
+	}
+	bc = extend(t, bc, 1)
+
+	hc := MakeHeaderChain(bc[0].Hash)
+	if err := hc.AddHeaders(bc.Headers(0, MaxHeadersPerMessage)); err != nil {
+		t.Fatal(err)
+	}
+
+	filters := bc.BlockFilters(0, MaxFiltersPerMessage)
+	if len(filters) != 3 {
+		t.Fatalf("got %d filters, want 3", len(filters))
+	}
+	var prevHeader []byte
+	for i := range filters {
+		if err := hc.CheckFilter(&filters[i], prevHeader); err != nil {
+			t.Fatalf("filter %d: %v", i, err)
+		}
+		prevHeader = filters[i].Header
+	}
+	if page := bc.BlockFilters(1, 1); len(page) != 1 || string(page[0].Header) != string(filters[1].Header) {
+		t.Fatal("filters from a height don't chain to the ones below")
+	}
+
+	// A node that leaves a transaction out of a filter
+	partial := filters[1]
+	partial.N = 0
+	partial.Data = nil
+	if hc.CheckFilter(&partial, filters[0].Header) == nil {
+		t.Fatal("filter with a different header chain accepted")
+	}
+	partial.Header = partial.CalcFilterHeader(filters[0].Header)
+	if hc.CheckFilter(&filters[2], partial.Header) == nil {
+		t.Fatal("filter chained to a forged filter accepted")
+	}
+
+	// Filters of blocks the header chain doesn't have
+	unknown := filters[2]
+	unknown.Height = 3
+	if hc.CheckFilter(&unknown, filters[1].Header) == nil {
+		t.Fatal("filter above the header chain accepted")
+	}
+	unknown = filters[2]
+	unknown.BlockHash = filters[1].BlockHash
+	if hc.CheckFilter(&unknown, filters[1].Header) == nil {
+		t.Fatal("filter of a block off the header chain accepted")
+	}
+}
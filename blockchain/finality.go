@@ -0,0 +1,221 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"strconv"
+	"sync"
+)
+
+const (
+	// VotePrevote - First round vote for a block hash
+	VotePrevote = "prevote"
+
+	// VotePrecommit - Second round vote for a block hash. A block
+	// is final once more than 2/3 of the validators precommit it
+	VotePrecommit = "precommit"
+)
+
+// Vote - A signed vote of a validator for a block hash at a height
+type Vote struct {
+	Type       string   `json:"Type"`
+	Height     uint64   `json:"Height"`
+	BlockHash  []byte   `json:"BlockHash"`
+	XVoter     *big.Int `json:"XVoter"`
+	YVoter     *big.Int `json:"YVoter"`
+	RSignature *big.Int `json:"RSignature"`
+	SSignature *big.Int `json:"SSignature"`
+}
+
+// FinalityGadget - Tallies the votes of a fixed validator set and
+// keeps track of the highest final block. Once a block is final,
+// ReplaceChain refuses any chain that doesn't contain it
+type FinalityGadget struct {
+	Validators []*ecdsa.PublicKey
+
+	// votes - vote type -> height -> voter -> block hash voted for
+	votes           map[string]map[uint64]map[string][]byte
+	finalizedHeight int64
+	finalizedHash   []byte
+	mux             sync.Mutex
+}
+
+// finality - The finality gadget ReplaceChain checks against.
+// nil means blocks are never final
+var finality *FinalityGadget
+
+// SetFinalityGadget - Sets the finality gadget used by every
+// blockchain in this process. Pass nil to disable finality
+func SetFinalityGadget(g *FinalityGadget) {
+	finality = g
+}
+
+// CurrentFinalityGadget - Returns the finality gadget in use, or nil
+func CurrentFinalityGadget() *FinalityGadget {
+	return finality
+}
+
+/************************************
+ * Votes
+************************************/
+
+// MakeVote - Creates a vote and signs it with the key of the validator
+func MakeVote(voteType string, height uint64, blockHash []byte, key *ecdsa.PrivateKey) (Vote, error) {
+	v := Vote{
+		Type:      voteType,
+		Height:    height,
+		BlockHash: blockHash,
+		XVoter:    key.X,
+		YVoter:    key.Y,
+	}
+
+	r, s, err := ecdsa.Sign(crand.Reader, key, v.HashVote())
+	if err != nil {
+		return v, err
+	}
+	v.RSignature = r
+	v.SSignature = s
+	return v, nil
+}
+
+// HashVote - Returns a SHA 256 hash of everything in the vote
+// other than the signature
+func (v *Vote) HashVote() []byte {
+	var buff string
+	buff += v.Type
+	buff += strconv.FormatUint(v.Height, Base)
+	buff += string(v.BlockHash)
+	buff += v.XVoter.String()
+	buff += v.YVoter.String()
+
+	hash := sha256.Sum256([]byte(buff))
+	return hash[:]
+}
+
+// VoteSignatureIsValid - Checks to see if the signature
+// of the vote is valid
+func (v *Vote) VoteSignatureIsValid() bool {
+	if v.XVoter == nil || v.YVoter == nil || v.RSignature == nil || v.SSignature == nil {
+		return false
+	}
+	pubKey := &ecdsa.PublicKey{Curve: elliptic.P384(), X: v.XVoter, Y: v.YVoter}
+	return ecdsa.Verify(pubKey, v.HashVote(), v.RSignature, v.SSignature)
+}
+
+/************************************
+ * Finality gadget
+************************************/
+
+// MakeFinalityGadget - FinalityGadget constructor
+func MakeFinalityGadget(validators []*ecdsa.PublicKey) *FinalityGadget {
+	return &FinalityGadget{
+		Validators:      validators,
+		votes:           make(map[string]map[uint64]map[string][]byte),
+		finalizedHeight: -1,
+	}
+}
+
+// isValidator - Returns true if the public key is in the validator set
+func (g *FinalityGadget) isValidator(x *big.Int, y *big.Int) bool {
+	for _, validator := range g.Validators {
+		if validator.X.Cmp(x) == 0 && validator.Y.Cmp(y) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// countVotes - Returns how many validators cast a vote of the given
+// type for the block hash at the height. Needs the lock held
+func (g *FinalityGadget) countVotes(voteType string, height uint64, blockHash []byte) int {
+	count := 0
+	for _, votedFor := range g.votes[voteType][height] {
+		if bytes.Compare(votedFor, blockHash) == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// hasQuorum - Returns true if more than 2/3 of the validators
+// are in the count
+func (g *FinalityGadget) hasQuorum(count int) bool {
+	return count*3 > len(g.Validators)*2
+}
+
+// AddVote - Checks a vote and adds it to the tally. Only the first
+// vote of a validator for a type and height counts. Returns true
+// if the vote made a new block final
+func (g *FinalityGadget) AddVote(v Vote) (bool, error) {
+	if v.Type != VotePrevote && v.Type != VotePrecommit {
+		return false, errors.New("AddVote: unknown vote type: " + v.Type)
+	}
+	if !g.isValidator(v.XVoter, v.YVoter) {
+		return false, errors.New("AddVote: voter isn't in the validator set")
+	}
+	if !v.VoteSignatureIsValid() {
+		return false, errors.New("AddVote: invalid vote signature")
+	}
+
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	if g.votes[v.Type] == nil {
+		g.votes[v.Type] = make(map[uint64]map[string][]byte)
+	}
+	if g.votes[v.Type][v.Height] == nil {
+		g.votes[v.Type][v.Height] = make(map[string][]byte)
+	}
+	voter := v.XVoter.String() + "," + v.YVoter.String()
+	if _, voted := g.votes[v.Type][v.Height][voter]; voted {
+		return false, nil
+	}
+	g.votes[v.Type][v.Height][voter] = v.BlockHash
+
+	// Check if the block just got enough precommits to become final
+	if v.Type != VotePrecommit || int64(v.Height) <= g.finalizedHeight {
+		return false, nil
+	}
+	if !g.hasQuorum(g.countVotes(VotePrecommit, v.Height, v.BlockHash)) {
+		return false, nil
+	}
+	g.finalizedHeight = int64(v.Height)
+	g.finalizedHash = v.BlockHash
+	return true, nil
+}
+
+// HasPrevoteQuorum - Returns true if more than 2/3 of the validators
+// prevoted the block hash at the height. Validators should only
+// precommit a block once this is true
+func (g *FinalityGadget) HasPrevoteQuorum(height uint64, blockHash []byte) bool {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	return g.hasQuorum(g.countVotes(VotePrevote, height, blockHash))
+}
+
+// Finalized - Returns the height and hash of the highest final
+// block. The height is -1 if no block is final yet
+func (g *FinalityGadget) Finalized() (int64, []byte) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	return g.finalizedHeight, g.finalizedHash
+}
+
+// ChainIsCompatible - Returns true if the chain contains the highest
+// final block, meaning that switching to it doesn't reorganize
+// anything below the final height
+func (g *FinalityGadget) ChainIsCompatible(bc Blockchain) bool {
+	height, hash := g.Finalized()
+	if height < 0 {
+		return true
+	}
+	if int64(len(bc)) <= height {
+		return false
+	}
+	return bytes.Compare(bc[height].Hash, hash) == 0
+}
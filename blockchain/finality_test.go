@@ -0,0 +1,101 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"testing"
+)
+
+// vote - Returns a vote signed by key, failing the test on error
+func vote(t *testing.T, voteType string, height uint64, blockHash []byte, key *ecdsa.PrivateKey) Vote {
+	t.Helper()
+	v, err := MakeVote(voteType, height, blockHash, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestFinalityQuorum(t *testing.T) {
+	var keys []*ecdsa.PrivateKey
+	var validators []*ecdsa.PublicKey
+	for i := 0; i < 4; i++ {
+		keys = append(keys, testKey(t))
+		validators = append(validators, &keys[i].PublicKey)
+	}
+	g := MakeFinalityGadget(validators)
+	hash := []byte("block")
+
+	// 3 of 4 is more than 2/3, 2 of 4 isn't
+	for i := 0; i < 2; i++ {
+		if _, err := g.AddVote(vote(t, VotePrevote, 5, hash, keys[i])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if g.HasPrevoteQuorum(5, hash) {
+		t.Fatal("prevote quorum with 2 of 4 validators")
+	}
+	g.AddVote(vote(t, VotePrevote, 5, hash, keys[2]))
+	if !g.HasPrevoteQuorum(5, hash) {
+		t.Fatal("no prevote quorum with 3 of 4 validators")
+	}
+
+	// A validator voting twice only counts once
+	for i := 0; i < 2; i++ {
+		if final, _ := g.AddVote(vote(t, VotePrecommit, 5, hash, keys[0])); final {
+			t.Fatal("one validator finalized a block")
+		}
+	}
+	g.AddVote(vote(t, VotePrecommit, 5, hash, keys[1]))
+	if height, _ := g.Finalized(); height != -1 {
+		t.Fatal("block final with 2 of 4 precommits")
+	}
+	if final, err := g.AddVote(vote(t, VotePrecommit, 5, hash, keys[2])); !final || err != nil {
+		t.Fatalf("third precommit: %v, %v", final, err)
+	}
+	if height, finalHash := g.Finalized(); height != 5 || string(finalHash) != string(hash) {
+		t.Fatalf("finalized %d", height)
+	}
+
+	outsider := testKey(t)
+	if _, err := g.AddVote(vote(t, VotePrecommit, 6, hash, outsider)); err == nil {
+		t.Fatal("vote from outside the validator set accepted")
+	}
+	forged := vote(t, VotePrecommit, 6, hash, keys[3])
+	forged.Height = 7
+	if _, err := g.AddVote(forged); err == nil {
+		t.Fatal("vote with a broken signature accepted")
+	}
+	if _, err := g.AddVote(vote(t, "vote", 6, hash, keys[3])); err == nil {
+		t.Fatal("unknown vote type accepted")
+	}
+}
+
+func TestReplaceChainKeepsFinalBlock(t *testing.T) {
+	defer SetFinalityGadget(CurrentFinalityGadget())
+	key := testKey(t)
+	g := MakeFinalityGadget([]*ecdsa.PublicKey{&key.PublicKey})
+	SetFinalityGadget(g)
+
+	bc := extend(t, genesisChain(t), 2)
+	if _, err := g.AddVote(vote(t, VotePrecommit, 1, bc[1].Hash, key)); err != nil {
+		t.Fatal(err)
+	}
+	if !g.ChainIsCompatible(bc) || g.ChainIsCompatible(bc[:1]) {
+		t.Fatal("compatibility doesn't follow the final block")
+	}
+
+	// A heavier fork below the final block
+	fork := extend(t, bc[:1], 4)
+	if g.ChainIsCompatible(fork) {
+		t.Fatal("fork below the final block is compatible")
+	}
+	if replaced, err := bc.ReplaceChain(fork); replaced || err == nil {
+		t.Fatal("reorganized below the final block")
+	}
+
+	// A heavier fork above it
+	fork = extend(t, bc[:2], 3)
+	if replaced, err := bc.ReplaceChain(fork); !replaced || err != nil {
+		t.Fatalf("ReplaceChain = %v, %v", replaced, err)
+	}
+}
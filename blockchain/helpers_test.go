@@ -0,0 +1,68 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"testing"
+)
+
+// testKey - Generates a P-384 key pair
+func testKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P384(), crand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// signTX - Signs a transaction with a P-384 key the way the
+// wallet does
+func signTX(t *testing.T, tx *Transaction, key *ecdsa.PrivateKey) {
+	t.Helper()
+	tx.KeyType = KeyP384
+	r, s, err := ecdsa.Sign(crand.Reader, key, tx.SigHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.RSignature = r
+	tx.SSignature = s
+}
+
+// transfer - Returns a signed transfer of amount from one
+// key to another
+func transfer(t *testing.T, from *ecdsa.PrivateKey, to *ecdsa.PublicKey, amount float64) Transaction {
+	t.Helper()
+	tx := Transaction{
+		Type:    TXTransfer,
+		XInput:  from.X,
+		YInput:  from.Y,
+		XOutput: to.X,
+		YOutput: to.Y,
+		Amount:  amount,
+	}
+	signTX(t, &tx, from)
+	return tx
+}
+
+// nextBlock - Returns a block with the given transactions, sealed
+// on top of the blockchain with the current consensus engine.
+// Timestamps go up by one a block
+func nextBlock(t *testing.T, bc *Blockchain, txs ...Transaction) Block {
+	t.Helper()
+	b := Block{Timestamp: 1700000000 + uint64(len(*bc)), TXs: txs}
+	if err := bc.SealBlock(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// genesisChain - Returns a blockchain made of a genesis block
+// holding the given transactions
+func genesisChain(t *testing.T, txs ...Transaction) Blockchain {
+	t.Helper()
+	var bc Blockchain
+	bc = append(bc, nextBlock(t, &bc, txs...))
+	return bc
+}
@@ -0,0 +1,150 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+)
+
+// HTLCPayload - What goes in the Data of an HTLC transaction. A lock
+// sets HashLock and Deadline, a claim sets LockTX and Preimage, and
+// a refund only sets LockTX. The Deadline follows the same rules as
+// Transaction.LockTime: below LockTimeThreshold it's a block height,
+// otherwise it's a Unix time
+type HTLCPayload struct {
+	HashLock []byte `json:"HashLock"`
+	Deadline uint64 `json:"Deadline"`
+	LockTX   []byte `json:"LockTX"`
+	Preimage []byte `json:"Preimage"`
+}
+
+// EncodeHTLCPayload - Serializes a payload for the Data of an
+// HTLC transaction
+func EncodeHTLCPayload(p HTLCPayload) ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// DecodeHTLCPayload - Deserializes the Data of an HTLC transaction
+func DecodeHTLCPayload(data []byte) (*HTLCPayload, error) {
+	var p HTLCPayload
+	err := json.Unmarshal(data, &p)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// FindHTLCLock - Finds the lock transaction with the given hash on the
+// blockchain. Also returns true if a claim or refund already settled it
+func (bc *Blockchain) FindHTLCLock(lockHash []byte) (*Transaction, *HTLCPayload, bool) {
+	var lock *Transaction
+	var payload *HTLCPayload
+	settled := false
+
+	for i := range *bc {
+		for j := range (*bc)[i].TXs {
+			tx := &(*bc)[i].TXs[j]
+			switch tx.Type {
+			case TXHTLCLock:
+				if lock == nil && bytes.Compare(tx.HashTransaction(), lockHash) == 0 {
+					p, err := DecodeHTLCPayload(tx.Data)
+					if err != nil {
+						continue
+					}
+					lock = tx
+					payload = p
+				}
+			case TXHTLCClaim, TXHTLCRefund:
+				p, err := DecodeHTLCPayload(tx.Data)
+				if err == nil && bytes.Compare(p.LockTX, lockHash) == 0 {
+					settled = true
+				}
+			}
+		}
+	}
+
+	return lock, payload, settled
+}
+
+// htlcSettledIn - Returns true if one of the transactions already
+// claims or refunds the HTLC the transaction claims or refunds.
+// FindHTLCLock only sees the blockchain, so blocks and the pool
+// check this against the transactions ahead of it
+func (t *Transaction) htlcSettledIn(txs []Transaction) bool {
+	if t.Type != TXHTLCClaim && t.Type != TXHTLCRefund {
+		return false
+	}
+	p, err := DecodeHTLCPayload(t.Data)
+	if err != nil {
+		return false
+	}
+	for i := range txs {
+		if txs[i].Type != TXHTLCClaim && txs[i].Type != TXHTLCRefund {
+			continue
+		}
+		other, err := DecodeHTLCPayload(txs[i].Data)
+		if err == nil && bytes.Compare(other.LockTX, p.LockTX) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// htlcDeadlinePassed - Returns true if the next block on top of the
+// blockchain is at or past the deadline. Unix time deadlines are
+// compared against the timestamp of the last block, so every node
+// gets the same answer
+func (bc *Blockchain) htlcDeadlinePassed(deadline uint64) bool {
+	if deadline < LockTimeThreshold {
+		return uint64(len(*bc)) >= deadline
+	}
+	if len(*bc) == 0 {
+		return false
+	}
+	return (*bc)[len(*bc)-1].Timestamp >= deadline
+}
+
+// htlcTransactionIsValid - Checks an HTLC transaction against the
+// locks on the blockchain
+func (t *Transaction) htlcTransactionIsValid(bc *Blockchain) bool {
+	p, err := DecodeHTLCPayload(t.Data)
+	if err != nil {
+		return false
+	}
+
+	if t.Type == TXHTLCLock {
+		return t.Amount > 0 && len(p.HashLock) == sha256.Size && p.Deadline > 0
+	}
+
+	lock, lockPayload, settled := bc.FindHTLCLock(p.LockTX)
+	if lock == nil || settled || t.Amount != lock.Amount {
+		return false
+	}
+
+	switch t.Type {
+	case TXHTLCClaim:
+		// Only the recipient can claim, only before the deadline,
+		// and only with the right preimage
+		if accountKey(t.XInput, t.YInput) != accountKey(lock.XOutput, lock.YOutput) {
+			return false
+		}
+		if bc.htlcDeadlinePassed(lockPayload.Deadline) {
+			return false
+		}
+		hash := sha256.Sum256(p.Preimage)
+		return bytes.Compare(hash[:], lockPayload.HashLock) == 0
+	case TXHTLCRefund:
+		// Only the sender can take the coins back, and the refund
+		// has to be time-locked until the deadline so it can't
+		// go into a block any earlier
+		if accountKey(t.XInput, t.YInput) != accountKey(lock.XInput, lock.YInput) {
+			return false
+		}
+		if (t.LockTime < LockTimeThreshold) != (lockPayload.Deadline < LockTimeThreshold) {
+			return false
+		}
+		return t.LockTime >= lockPayload.Deadline
+	}
+
+	return false
+}
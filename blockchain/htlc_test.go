@@ -0,0 +1,68 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"testing"
+)
+
+// htlcTX - Returns a signed HTLC transaction from key
+func htlcTX(t *testing.T, txType uint32, key *ecdsa.PrivateKey, to *ecdsa.PublicKey, amount float64, p HTLCPayload) Transaction {
+	t.Helper()
+	data, err := EncodeHTLCPayload(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := transfer(t, key, to, amount)
+	tx.Type = txType
+	tx.Data = data
+	signTX(t, &tx, key)
+	return tx
+}
+
+func TestHTLCDoubleClaim(t *testing.T) {
+	treasury := testKey(t)
+	alice := testKey(t)
+	bob := testKey(t)
+	bc := genesisChain(t, transfer(t, treasury, &alice.PublicKey, 10))
+
+	preimage := []byte("secret")
+	hashLock := sha256.Sum256(preimage)
+	lock := htlcTX(t, TXHTLCLock, alice, &bob.PublicKey, 5, HTLCPayload{HashLock: hashLock[:], Deadline: 100})
+	b := nextBlock(t, &bc, lock)
+	if !bc.AddBlock(&b) {
+		t.Fatal("lock rejected")
+	}
+
+	claim := func(timestamp uint64) Transaction {
+		tx := htlcTX(t, TXHTLCClaim, bob, &bob.PublicKey, 5, HTLCPayload{LockTX: lock.HashTransaction(), Preimage: preimage})
+		tx.Timestamp = timestamp
+		signTX(t, &tx, bob)
+		return tx
+	}
+	first, second := claim(1), claim(2)
+
+	double := nextBlock(t, &bc, first, second)
+	if bc.AddBlock(&double) {
+		t.Fatal("two claims of the same HTLC in one block accepted")
+	}
+
+	pool := MakeTXPool()
+	if err := pool.AddTransaction(&bc, first); err != nil {
+		t.Fatal(err)
+	}
+	if pool.AddTransaction(&bc, second) == nil {
+		t.Fatal("second claim of the same HTLC accepted into the pool")
+	}
+
+	b = nextBlock(t, &bc, first)
+	if !bc.AddBlock(&b) {
+		t.Fatal("claim rejected")
+	}
+	if balance := bc.CalcAccountBalanceOnBC(&bob.PublicKey, -1); balance != 5 {
+		t.Fatalf("balance after the claim = %v, want 5", balance)
+	}
+	if MakeTXPool().AddTransaction(&bc, second) == nil {
+		t.Fatal("claim of a settled HTLC accepted")
+	}
+}
@@ -0,0 +1,91 @@
+package blockchain
+
+import (
+	"bytes"
+	"log"
+	"sync"
+)
+
+// Indexer - Something that keeps data derived from the blockchain
+// (an index, a cache...) in step with it. ConnectBlock gets called
+// when a block becomes part of the chain and DisconnectBlock when
+// a reorganization takes it back out, tip first. An error doesn't
+// stop the block from being connected or disconnected; it gets
+// logged, since the indexer is out of step with the chain from
+// then on
+type Indexer interface {
+	ConnectBlock(b *Block, height uint64) error
+	DisconnectBlock(b *Block, height uint64) error
+}
+
+var indexers []Indexer
+var indexerMux sync.Mutex
+
+// RegisterIndexer - Adds an indexer that gets told about every block
+// connected to or disconnected from any blockchain in this process
+func RegisterIndexer(ix Indexer) {
+	indexerMux.Lock()
+	defer indexerMux.Unlock()
+	indexers = append(indexers, ix)
+}
+
+// UnregisterIndexer - Stops telling an indexer about blocks
+func UnregisterIndexer(ix Indexer) {
+	indexerMux.Lock()
+	defer indexerMux.Unlock()
+	for i := range indexers {
+		if indexers[i] == ix {
+			indexers = append(indexers[:i], indexers[i+1:]...)
+			return
+		}
+	}
+}
+
+// connectBlock - Tells every indexer and subscriber about
+// a connected block
+func connectBlock(b *Block, height uint64) {
+	indexerMux.Lock()
+	for _, ix := range indexers {
+		if err := ix.ConnectBlock(b, height); err != nil {
+			log.Printf("connectBlock: indexer failed at height %d: %v", height, err)
+		}
+	}
+	indexerMux.Unlock()
+	publishBlock(EventBlockConnected, b, height)
+}
+
+// disconnectBlock - Tells every indexer and subscriber about
+// a disconnected block
+func disconnectBlock(b *Block, height uint64) {
+	indexerMux.Lock()
+	for _, ix := range indexers {
+		if err := ix.DisconnectBlock(b, height); err != nil {
+			log.Printf("disconnectBlock: indexer failed at height %d: %v", height, err)
+		}
+	}
+	indexerMux.Unlock()
+	publishBlock(EventBlockDisconnected, b, height)
+}
+
+// forkPoint - Returns the height of the first block where the two
+// chains differ
+func forkPoint(a Blockchain, b Blockchain) int {
+	i := 0
+	for i < len(a) && i < len(b) && bytes.Compare(a[i].Hash, b[i].Hash) == 0 {
+		i++
+	}
+	return i
+}
+
+// reorganize - Tells the indexers about switching from the old chain
+// to the new one: the old blocks above the fork point get
+// disconnected, tip first, then the new ones get connected
+func reorganize(oldChain Blockchain, newChain Blockchain) {
+	fork := forkPoint(oldChain, newChain)
+	for i := len(oldChain) - 1; i >= fork; i-- {
+		disconnectBlock(&oldChain[i], uint64(i))
+	}
+	for i := fork; i < len(newChain); i++ {
+		connectBlock(&newChain[i], uint64(i))
+	}
+}
@@ -0,0 +1,93 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secp256k1ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// Key types a transaction input can be signed with. The input of
+// an Ed25519 transaction carries the 32 byte public key in XInput
+// and zero in YInput, and its signature is split into the first
+// and last 32 bytes
+const (
+	KeyP384      = 0
+	KeyEd25519   = 1
+	KeySecp256k1 = 2
+)
+
+// KeyTypeIsValid - Returns true if the key type is one of the
+// supported signature schemes
+func KeyTypeIsValid(keyType uint32) bool {
+	return keyType == KeyP384 || keyType == KeyEd25519 || keyType == KeySecp256k1
+}
+
+// Ed25519AccountID - Returns the account of an Ed25519 public key,
+// in the same form as the account of any other key
+func Ed25519AccountID(pubKey ed25519.PublicKey) *ecdsa.PublicKey {
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P384(),
+		X:     new(big.Int).SetBytes(pubKey),
+		Y:     new(big.Int),
+	}
+}
+
+// fixedBytes - Writes a number into size big endian bytes. Returns
+// nil if it doesn't fit
+func fixedBytes(n *big.Int, size int) []byte {
+	if n == nil || n.Sign() < 0 || n.BitLen() > size*8 {
+		return nil
+	}
+	return n.FillBytes(make([]byte, size))
+}
+
+// verifySignature - Checks a signature over hash made by the key
+// (x, y) of the given type
+func verifySignature(keyType uint32, x *big.Int, y *big.Int, hash []byte, r *big.Int, s *big.Int) bool {
+	if x == nil || y == nil || r == nil || s == nil {
+		return false
+	}
+
+	switch keyType {
+	case KeyP384:
+		pubKey := &ecdsa.PublicKey{Curve: elliptic.P384(), X: x, Y: y}
+		return ecdsa.Verify(pubKey, hash, r, s)
+	case KeyEd25519:
+		pubKey := fixedBytes(x, ed25519.PublicKeySize)
+		rBytes := fixedBytes(r, ed25519.SignatureSize/2)
+		sBytes := fixedBytes(s, ed25519.SignatureSize/2)
+		if pubKey == nil || y.Sign() != 0 || rBytes == nil || sBytes == nil {
+			return false
+		}
+		return ed25519.Verify(pubKey, hash, append(rBytes, sBytes...))
+	case KeySecp256k1:
+		xBytes := fixedBytes(x, 32)
+		yBytes := fixedBytes(y, 32)
+		if xBytes == nil || yBytes == nil {
+			return false
+		}
+		// Parsing the uncompressed key checks that it's on the curve
+		pubKey, err := secp256k1.ParsePubKey(append(append([]byte{0x04}, xBytes...), yBytes...))
+		if err != nil {
+			return false
+		}
+		// r.Bytes() drops the sign and SetByteSlice the high bytes, so
+		// a negative or oversized r or s would verify as another
+		// number and change the hash of the transaction
+		rBytes := fixedBytes(r, 32)
+		sBytes := fixedBytes(s, 32)
+		if r.Sign() <= 0 || s.Sign() <= 0 || rBytes == nil || sBytes == nil {
+			return false
+		}
+		var rScalar, sScalar secp256k1.ModNScalar
+		if rScalar.SetByteSlice(rBytes) || sScalar.SetByteSlice(sBytes) {
+			return false
+		}
+		return secp256k1ecdsa.NewSignature(&rScalar, &sScalar).Verify(hash, pubKey)
+	}
+	return false
+}
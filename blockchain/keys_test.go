@@ -0,0 +1,76 @@
+package blockchain
+
+import (
+	"crypto/ed25519"
+	crand "crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secp256k1ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+func TestEd25519Accounts(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(crand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := Ed25519AccountID(pubKey)
+	hash := []byte("hash")
+	sig := ed25519.Sign(privKey, hash)
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+
+	if !verifySignature(KeyEd25519, account.X, account.Y, hash, r, s) {
+		t.Fatal("signature rejected")
+	}
+	if verifySignature(KeyEd25519, account.X, big.NewInt(1), hash, r, s) {
+		t.Fatal("account with a non-zero Y accepted")
+	}
+	tooLong := new(big.Int).Lsh(big.NewInt(1), 256)
+	if verifySignature(KeyEd25519, account.X, account.Y, hash, tooLong, s) {
+		t.Fatal("oversized signature half accepted")
+	}
+	if verifySignature(KeyEd25519, nil, account.Y, hash, r, s) {
+		t.Fatal("missing key accepted")
+	}
+}
+
+func TestSecp256k1KeyOffCurve(t *testing.T) {
+	if verifySignature(KeySecp256k1, big.NewInt(1), big.NewInt(1), []byte("hash"), big.NewInt(1), big.NewInt(1)) {
+		t.Fatal("point off the curve accepted")
+	}
+	if KeyTypeIsValid(3) {
+		t.Fatal("unknown key type is valid")
+	}
+}
+
+func TestSecp256k1SignatureMalleability(t *testing.T) {
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey := key.ToECDSA().PublicKey
+	hash := []byte("01234567890123456789012345678901")
+	sig := secp256k1ecdsa.SignCompact(key, hash, false)
+	r := new(big.Int).SetBytes(sig[1:33])
+	s := new(big.Int).SetBytes(sig[33:])
+	if !verifySignature(KeySecp256k1, pubKey.X, pubKey.Y, hash, r, s) {
+		t.Fatal("valid signature rejected")
+	}
+
+	// Each of these has the same bytes as r or s once the sign or
+	// the high bytes are dropped
+	above := new(big.Int).Lsh(big.NewInt(1), 256)
+	variants := map[string][2]*big.Int{
+		"negative r":  {new(big.Int).Neg(r), s},
+		"negative s":  {r, new(big.Int).Neg(s)},
+		"oversized r": {new(big.Int).Add(r, above), s},
+		"zero s":      {r, new(big.Int)},
+	}
+	for name, v := range variants {
+		if verifySignature(KeySecp256k1, pubKey.X, pubKey.Y, hash, v[0], v[1]) {
+			t.Fatalf("signature with a %s accepted", name)
+		}
+	}
+}
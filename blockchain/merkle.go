@@ -0,0 +1,133 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+)
+
+// MerkleProof - Proves that a transaction is in a block without
+// the rest of the block. Siblings holds the hashes the transaction
+// hash gets combined with on the way up to the Merkle root, bottom
+// first
+type MerkleProof struct {
+	TXHash    []byte   `json:"TXHash"`
+	BlockHash []byte   `json:"BlockHash"`
+	Height    uint64   `json:"Height"`
+	Index     int      `json:"Index"`
+	NumTXs    int      `json:"NumTXs"`
+	Siblings  [][]byte `json:"Siblings"`
+}
+
+// hashMerklePair - Hashes two nodes of a Merkle tree into their parent
+func hashMerklePair(left []byte, right []byte) []byte {
+	hash := sha256.Sum256(append(append([]byte{}, left...), right...))
+	return hash[:]
+}
+
+// merkleLevel - Hashes one level of a Merkle tree into the level above
+// it. An odd node out moves up unchanged rather than being paired
+// with itself, so no two transaction lists share a root
+func merkleLevel(level [][]byte) [][]byte {
+	var next [][]byte
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+		} else {
+			next = append(next, hashMerklePair(level[i], level[i+1]))
+		}
+	}
+	return next
+}
+
+// merkleLeaves - Returns the hash of every transaction
+func merkleLeaves(txs []Transaction) [][]byte {
+	leaves := make([][]byte, len(txs))
+	for i := range txs {
+		leaves[i] = txs[i].HashTransaction()
+	}
+	return leaves
+}
+
+// CalcMerkleRoot - Returns the root of the Merkle tree over the hashes
+// of the transactions. Returns nil if there are no transactions
+func CalcMerkleRoot(txs []Transaction) []byte {
+	if len(txs) == 0 {
+		return nil
+	}
+	level := merkleLeaves(txs)
+	for len(level) > 1 {
+		level = merkleLevel(level)
+	}
+	return level[0]
+}
+
+// MakeMerkleProof - Builds the Merkle proof of the transaction at the
+// given index of a block at the given height
+func MakeMerkleProof(b *Block, height uint64, index int) (*MerkleProof, error) {
+	if index < 0 || index >= len(b.TXs) {
+		return nil, errors.New("MakeMerkleProof: no transaction at that index")
+	}
+
+	proof := &MerkleProof{
+		TXHash:    b.TXs[index].HashTransaction(),
+		BlockHash: b.Hash,
+		Height:    height,
+		Index:     index,
+		NumTXs:    len(b.TXs),
+	}
+	level := merkleLeaves(b.TXs)
+	for i := index; len(level) > 1; i /= 2 {
+		sibling := i ^ 1
+		if sibling < len(level) {
+			proof.Siblings = append(proof.Siblings, level[sibling])
+		}
+		level = merkleLevel(level)
+	}
+	return proof, nil
+}
+
+// FindMerkleProof - Looks for a transaction on the blockchain, tip
+// first, and builds its Merkle proof
+// (@TODO-OPTIMIZE)
+func (bc *Blockchain) FindMerkleProof(txHash []byte) (*MerkleProof, error) {
+	for i := len(*bc) - 1; i >= 0; i-- {
+		b := &(*bc)[i]
+		for j := range b.TXs {
+			if bytes.Compare(b.TXs[j].HashTransaction(), txHash) == 0 {
+				return MakeMerkleProof(b, uint64(i), j)
+			}
+		}
+	}
+	return nil, errors.New("FindMerkleProof: transaction isn't on the blockchain")
+}
+
+// MerkleRoot - Returns the Merkle root the proof leads up to, or nil
+// if the proof has the wrong shape
+func (p *MerkleProof) MerkleRoot() []byte {
+	if p.Index < 0 || p.Index >= p.NumTXs {
+		return nil
+	}
+
+	hash := p.TXHash
+	used := 0
+	for i, n := p.Index, p.NumTXs; n > 1; i, n = i/2, (n+1)/2 {
+		// The odd node out has no sibling on this level
+		if i == n-1 && n%2 == 1 {
+			continue
+		}
+		if used == len(p.Siblings) {
+			return nil
+		}
+		if i%2 == 0 {
+			hash = hashMerklePair(hash, p.Siblings[used])
+		} else {
+			hash = hashMerklePair(p.Siblings[used], hash)
+		}
+		used++
+	}
+	if used != len(p.Siblings) {
+		return nil
+	}
+	return hash
+}
@@ -0,0 +1,130 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"math/big"
+	"sort"
+)
+
+// MultisigKey - One of the public keys of a multisig account
+type MultisigKey struct {
+	X *big.Int `json:"X"`
+	Y *big.Int `json:"Y"`
+}
+
+// MultisigPolicy - An M-of-N multisig account: at least Threshold
+// of the Keys have to sign to spend from it
+type MultisigPolicy struct {
+	Threshold uint32        `json:"Threshold"`
+	Keys      []MultisigKey `json:"Keys"`
+}
+
+// TXSignature - A signature of one of the keys of a multisig account
+type TXSignature struct {
+	XSigner    *big.Int `json:"XSigner"`
+	YSigner    *big.Int `json:"YSigner"`
+	RSignature *big.Int `json:"RSignature"`
+	SSignature *big.Int `json:"SSignature"`
+}
+
+// MakeMultisigPolicy - MultisigPolicy constructor. The order of
+// the keys doesn't matter
+func MakeMultisigPolicy(threshold uint32, keys []*ecdsa.PublicKey) MultisigPolicy {
+	p := MultisigPolicy{Threshold: threshold}
+	for _, key := range keys {
+		p.Keys = append(p.Keys, MultisigKey{X: key.X, Y: key.Y})
+	}
+	sort.Slice(p.Keys, func(i, j int) bool {
+		if c := p.Keys[i].X.Cmp(p.Keys[j].X); c != 0 {
+			return c < 0
+		}
+		return p.Keys[i].Y.Cmp(p.Keys[j].Y) < 0
+	})
+	return p
+}
+
+// convToBytes - DO NOT use this for serialization. This
+// should only used when shoving a policy into a hash function
+func (p *MultisigPolicy) convToBytes() []byte {
+	var buff hashBuffer
+	buff.putUint(uint64(p.Threshold))
+	buff.putUint(uint64(len(p.Keys)))
+	for _, key := range p.Keys {
+		buff.putBig(key.X)
+		buff.putBig(key.Y)
+	}
+	return buff
+}
+
+// PolicyIsValid - Returns true if the threshold can be met and
+// no key appears twice
+func (p *MultisigPolicy) PolicyIsValid() bool {
+	if p.Threshold == 0 || int(p.Threshold) > len(p.Keys) {
+		return false
+	}
+	seen := make(map[string]bool)
+	for _, key := range p.Keys {
+		if key.X == nil || key.Y == nil {
+			return false
+		}
+		id := key.X.String() + "," + key.Y.String()
+		if seen[id] {
+			return false
+		}
+		seen[id] = true
+	}
+	return true
+}
+
+// AccountID - Derives the identifier of the multisig account from
+// its policy. It's used in XInput/YInput and XOutput/YOutput the
+// same way a regular public key is, so the balance functions
+// work on it too. It is NOT a point on the curve, so it can't
+// verify anything by itself
+func (p *MultisigPolicy) AccountID() *ecdsa.PublicKey {
+	return deriveAccountID("multisig", p.convToBytes())
+}
+
+// multisigIsValid - Checks that the input of the transaction is the
+// account of its multisig policy and that at least Threshold
+// different keys of the policy signed the transaction
+func (t *Transaction) multisigIsValid() bool {
+	if !t.Multisig.PolicyIsValid() {
+		return false
+	}
+	if !t.isInput(t.Multisig.AccountID()) {
+		return false
+	}
+
+	sigHash := t.SigHash()
+	signed := make(map[string]bool)
+	for _, sig := range t.Signatures {
+		if sig.XSigner == nil || sig.YSigner == nil || sig.RSignature == nil || sig.SSignature == nil {
+			continue
+		}
+		id := sig.XSigner.String() + "," + sig.YSigner.String()
+		if signed[id] {
+			continue
+		}
+
+		// The signer has to be one of the keys of the policy
+		member := false
+		for _, key := range t.Multisig.Keys {
+			if key.X.Cmp(sig.XSigner) == 0 && key.Y.Cmp(sig.YSigner) == 0 {
+				member = true
+				break
+			}
+		}
+		if !member {
+			continue
+		}
+
+		pubKey := &ecdsa.PublicKey{Curve: elliptic.P384(), X: sig.XSigner, Y: sig.YSigner}
+		if ecdsa.Verify(pubKey, sigHash, sig.RSignature, sig.SSignature) {
+			signed[id] = true
+		}
+	}
+
+	return len(signed) >= int(t.Multisig.Threshold)
+}
@@ -0,0 +1,102 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	crand "crypto/rand"
+	"testing"
+)
+
+// cosign - Adds the signature of key to a multisig transaction
+func cosign(t *testing.T, tx *Transaction, key *ecdsa.PrivateKey) {
+	t.Helper()
+	r, s, err := ecdsa.Sign(crand.Reader, key, tx.SigHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Signatures = append(tx.Signatures, TXSignature{XSigner: key.X, YSigner: key.Y, RSignature: r, SSignature: s})
+}
+
+func TestMultisigSpend(t *testing.T) {
+	a, b, c := testKey(t), testKey(t), testKey(t)
+	outsider := testKey(t)
+	policy := MakeMultisigPolicy(2, []*ecdsa.PublicKey{&a.PublicKey, &b.PublicKey, &c.PublicKey})
+	reordered := MakeMultisigPolicy(2, []*ecdsa.PublicKey{&c.PublicKey, &a.PublicKey, &b.PublicKey})
+	account := policy.AccountID()
+	if account.X.Cmp(reordered.AccountID().X) != 0 {
+		t.Fatal("the order of the keys changes the account")
+	}
+
+	treasury := testKey(t)
+	fund := transfer(t, treasury, account, 10)
+	bc := genesisChain(t, fund)
+
+	spend := func(signers ...*ecdsa.PrivateKey) Transaction {
+		tx := Transaction{
+			Type:    TXTransfer,
+			XInput:  account.X,
+			YInput:  account.Y,
+			XOutput: outsider.X,
+			YOutput: outsider.Y,
+			Amount:  4,
+		}
+		tx.Multisig = &policy
+		for _, key := range signers {
+			cosign(t, &tx, key)
+		}
+		return tx
+	}
+
+	rejected := map[string]Transaction{
+		"one signature":         spend(a),
+		"the same signer twice": spend(a, a),
+		"a key outside the set": spend(a, outsider),
+		"no signatures":         spend(),
+	}
+	for name, tx := range rejected {
+		if tx.TransactionSignatureIsValid() {
+			t.Fatalf("%s: accepted", name)
+		}
+	}
+
+	tx := spend(b, c)
+	if !tx.TransactionSignatureIsValid() {
+		t.Fatal("2 of 3 signatures rejected")
+	}
+	tampered := tx
+	tampered.Amount = 9
+	if tampered.TransactionSignatureIsValid() {
+		t.Fatal("amount changed after signing accepted")
+	}
+	other := MakeMultisigPolicy(1, []*ecdsa.PublicKey{&b.PublicKey})
+	swapped := tx
+	swapped.Multisig = &other
+	if swapped.TransactionSignatureIsValid() {
+		t.Fatal("policy swapped for an easier one accepted")
+	}
+
+	block := nextBlock(t, &bc, tx)
+	if !bc.AddBlock(&block) {
+		t.Fatal("multisig spend rejected")
+	}
+	if balance := bc.CalcAccountBalanceOnBC(account, -1); balance != 6 {
+		t.Fatalf("multisig balance = %v, want 6", balance)
+	}
+}
+
+func TestMultisigPolicyIsValid(t *testing.T) {
+	a, b := testKey(t), testKey(t)
+	invalid := []MultisigPolicy{
+		MakeMultisigPolicy(0, []*ecdsa.PublicKey{&a.PublicKey}),
+		MakeMultisigPolicy(3, []*ecdsa.PublicKey{&a.PublicKey, &b.PublicKey}),
+		MakeMultisigPolicy(2, []*ecdsa.PublicKey{&a.PublicKey, &a.PublicKey}),
+	}
+	for i, p := range invalid {
+		if p.PolicyIsValid() {
+			t.Fatalf("policy %d accepted", i)
+		}
+	}
+	p := MakeMultisigPolicy(2, []*ecdsa.PublicKey{&a.PublicKey, &b.PublicKey})
+	if !p.PolicyIsValid() {
+		t.Fatal("2 of 2 policy rejected")
+	}
+}
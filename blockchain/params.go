@@ -0,0 +1,122 @@
+package blockchain
+
+import (
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// PoWHashSHA256 - The proof of work hash is the block hash itself
+	PoWHashSHA256 = "sha256"
+
+	// PoWHashScrypt - The proof of work hash is scrypt over the block hash
+	PoWHashScrypt = "scrypt"
+
+	// PoWHashArgon2 - The proof of work hash is Argon2id over the block hash
+	PoWHashArgon2 = "argon2id"
+
+	// powHashLen - Length of the memory-hard hashes (in bytes)
+	powHashLen = 32
+)
+
+// ChainParams - The parameters every node of a chain has to agree on
+type ChainParams struct {
+	Name string `json:"Name"`
+
+	// AddressVersion - The first byte of every address, which keeps
+	// the addresses of different chains apart
+	AddressVersion byte `json:"AddressVersion"`
+
+	// PoWHash - Which hash the proof of work is checked against.
+	// One of PoWHashSHA256, PoWHashScrypt or PoWHashArgon2
+	PoWHash string `json:"PoWHash"`
+
+	// Scrypt cost parameters. ScryptN has to be a power of two
+	ScryptN int `json:"ScryptN"`
+	ScryptR int `json:"ScryptR"`
+	ScryptP int `json:"ScryptP"`
+
+	// Argon2id cost parameters. Argon2Memory is in KiB
+	Argon2Time    uint32 `json:"Argon2Time"`
+	Argon2Memory  uint32 `json:"Argon2Memory"`
+	Argon2Threads uint8  `json:"Argon2Threads"`
+
+	// SignalingWindow - How many blocks a version bits signaling
+	// window spans. Deployments can only change state at the
+	// start of a window
+	SignalingWindow uint64 `json:"SignalingWindow"`
+
+	// ActivationThreshold - How many blocks of a window have to
+	// signal for a deployment for it to lock in
+	ActivationThreshold uint64 `json:"ActivationThreshold"`
+
+	// Deployments - The soft forks being rolled out through
+	// version bits
+	Deployments []Deployment `json:"Deployments"`
+
+	// RuleSets - Changes to the consensus rules, each taking over
+	// at its activation height, or once its deployment is active.
+	// See RuleSetAt and ActiveRuleSet
+	RuleSets []RuleSet `json:"RuleSets"`
+}
+
+// params - The chain parameters currently in use
+var params = DefaultChainParams()
+
+// DefaultChainParams - Returns the parameters of the main chain
+func DefaultChainParams() ChainParams {
+	return ChainParams{
+		Name:           "main",
+		AddressVersion: 0x00,
+		PoWHash:        PoWHashSHA256,
+		ScryptN:        1 << 15,
+		ScryptR:        8,
+		ScryptP:        1,
+		Argon2Time:     1,
+		Argon2Memory:   64 * 1024,
+		Argon2Threads:  1,
+
+		SignalingWindow:     1000,
+		ActivationThreshold: 950,
+
+		// Every block of the main chain is hashed with HashFormatV2,
+		// so the proof of work commits to the whole header
+		RuleSets: []RuleSet{
+			{Name: "v2", ActivationHeight: 0, HashFormat: HashFormatV2},
+		},
+	}
+}
+
+// SetChainParams - Sets the chain parameters used by every
+// blockchain in this process. Call this before mining or
+// validating any blocks
+func SetChainParams(p ChainParams) {
+	params = p
+	resetDeploymentCache()
+}
+
+// ActiveChainParams - Returns the chain parameters in use
+func ActiveChainParams() ChainParams {
+	return params
+}
+
+// PoWHash - Runs the proof of work hash selected by the chain
+// parameters over a block hash. The block hash already commits
+// to every field of the block, so it is used as both the password
+// and the salt of the memory-hard functions.
+// Returns nil if the scrypt parameters are invalid, which no
+// difficulty above zero accepts
+func PoWHash(blockHash []byte) []byte {
+	switch params.PoWHash {
+	case PoWHashScrypt:
+		hash, err := scrypt.Key(blockHash, blockHash, params.ScryptN, params.ScryptR, params.ScryptP, powHashLen)
+		if err != nil {
+			return nil
+		}
+		return hash
+	case PoWHashArgon2:
+		return argon2.IDKey(blockHash, blockHash, params.Argon2Time, params.Argon2Memory, params.Argon2Threads, powHashLen)
+	}
+
+	return blockHash
+}
@@ -0,0 +1,65 @@
+package blockchain
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// cheapPoWParams - Chain parameters with the given proof of work
+// hash and cost parameters low enough for tests
+func cheapPoWParams(hash string) ChainParams {
+	p := DefaultChainParams()
+	p.PoWHash = hash
+	p.ScryptN = 16
+	p.ScryptR = 1
+	p.ScryptP = 1
+	p.Argon2Memory = 64
+	return p
+}
+
+func TestPoWHash(t *testing.T) {
+	defer SetChainParams(ActiveChainParams())
+	blockHash := []byte("block hash")
+
+	SetChainParams(cheapPoWParams(PoWHashSHA256))
+	if bytes.Compare(PoWHash(blockHash), blockHash) != 0 {
+		t.Fatal("the SHA-256 proof of work hash isn't the block hash")
+	}
+
+	SetChainParams(cheapPoWParams(PoWHashScrypt))
+	want, _ := scrypt.Key(blockHash, blockHash, 16, 1, 1, powHashLen)
+	if bytes.Compare(PoWHash(blockHash), want) != 0 {
+		t.Fatal("wrong scrypt proof of work hash")
+	}
+
+	SetChainParams(cheapPoWParams(PoWHashArgon2))
+	want = argon2.IDKey(blockHash, blockHash, 1, 64, 1, powHashLen)
+	if bytes.Compare(PoWHash(blockHash), want) != 0 {
+		t.Fatal("wrong Argon2id proof of work hash")
+	}
+
+	broken := cheapPoWParams(PoWHashScrypt)
+	broken.ScryptN = 15
+	SetChainParams(broken)
+	if PoWHash(blockHash) != nil || powIsValid(blockHash, 1) {
+		t.Fatal("invalid scrypt parameters accepted")
+	}
+}
+
+func TestMineWithMemoryHardHash(t *testing.T) {
+	defer SetChainParams(ActiveChainParams())
+	for _, hash := range []string{PoWHashScrypt, PoWHashArgon2} {
+		SetChainParams(cheapPoWParams(hash))
+		b := Block{Timestamp: 1700000000, Difficulty: 1}
+		b.MineBlock()
+		if !b.BlockHashIsValid() {
+			t.Fatalf("%s: mined block rejected", hash)
+		}
+		if PoWHash(b.Hash)[0] != 0 {
+			t.Fatalf("%s: proof of work hash doesn't meet the difficulty", hash)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+)
+
+// ProofOfAuthority - A consensus engine for private networks where
+// a fixed set of signers take turns producing blocks instead of
+// mining them. The signer of the block at height n must be
+// Signers[n % len(Signers)], and it has to sign the block with
+// the P-384 key the wallet package generated for it
+type ProofOfAuthority struct {
+	Signers []*ecdsa.PublicKey
+
+	// Key - The private key this node seals blocks with. Leave
+	// it nil if this node only validates
+	Key *ecdsa.PrivateKey
+}
+
+// MakeProofOfAuthority - ProofOfAuthority constructor
+func MakeProofOfAuthority(signers []*ecdsa.PublicKey, key *ecdsa.PrivateKey) *ProofOfAuthority {
+	return &ProofOfAuthority{Signers: signers, Key: key}
+}
+
+// InTurnSigner - Returns the signer that is allowed to produce
+// the block at the given height
+func (poa *ProofOfAuthority) InTurnSigner(height uint64) *ecdsa.PublicKey {
+	if len(poa.Signers) == 0 {
+		return nil
+	}
+	return poa.Signers[height%uint64(len(poa.Signers))]
+}
+
+// IsSigner - Returns true if the public key is in the signer set
+func (poa *ProofOfAuthority) IsSigner(x *big.Int, y *big.Int) bool {
+	for _, signer := range poa.Signers {
+		if signer.X.Cmp(x) == 0 && signer.Y.Cmp(y) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Seal - Signs the block with this node's key. Fails if this
+// node has no key or if it isn't its turn
+func (poa *ProofOfAuthority) Seal(bc *Blockchain, b *Block) error {
+	if poa.Key == nil {
+		return errors.New("ProofOfAuthority: no signing key configured")
+	}
+	inTurn := poa.InTurnSigner(b.Index)
+	if inTurn == nil {
+		return errors.New("ProofOfAuthority: empty signer set")
+	}
+	if inTurn.X.Cmp(poa.Key.X) != 0 || inTurn.Y.Cmp(poa.Key.Y) != 0 {
+		return errors.New("ProofOfAuthority: not this signer's turn")
+	}
+	return b.SignBlock(poa.Key)
+}
+
+// VerifySeal - Checks that the block sits at the next height of the
+// chain, was signed by a member of the signer set, that it was
+// that member's turn, and that the signature is valid
+func (poa *ProofOfAuthority) VerifySeal(bc *Blockchain, b *Block) bool {
+	if b.Index != uint64(len(*bc)) {
+		return false
+	}
+	if !b.BlockSignatureIsValid() {
+		return false
+	}
+	if !poa.IsSigner(b.XSigner, b.YSigner) {
+		return false
+	}
+	inTurn := poa.InTurnSigner(b.Index)
+	if inTurn.Curve != elliptic.P384() {
+		return false
+	}
+	return inTurn.X.Cmp(b.XSigner) == 0 && inTurn.Y.Cmp(b.YSigner) == 0
+}
+
+// CalcDifficulty - Nothing is mined, so there is no difficulty
+func (poa *ProofOfAuthority) CalcDifficulty(bc *Blockchain) uint32 {
+	return 0
+}
+
+// BlockWeight - Every block counts the same
+func (poa *ProofOfAuthority) BlockWeight(b *Block) *big.Int {
+	return big.NewInt(1)
+}
+
+// ForkChoice - The longest chain wins
+func (poa *ProofOfAuthority) ForkChoice(current Blockchain, candidate Blockchain) bool {
+	return len(candidate) > len(current)
+}
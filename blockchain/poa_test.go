@@ -0,0 +1,70 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"testing"
+)
+
+func TestProofOfAuthority(t *testing.T) {
+	defer SetConsensusEngine(CurrentConsensusEngine())
+	alice := testKey(t)
+	bob := testKey(t)
+	outsider := testKey(t)
+	signers := []*ecdsa.PublicKey{&alice.PublicKey, &bob.PublicKey}
+	validator := MakeProofOfAuthority(signers, nil)
+
+	// Signers take turns: alice at even heights, bob at odd ones
+	keys := []*ecdsa.PrivateKey{alice, bob}
+	var bc Blockchain
+	for height := 0; height < 4; height++ {
+		SetConsensusEngine(MakeProofOfAuthority(signers, keys[height%2]))
+		b := nextBlock(t, &bc)
+		SetConsensusEngine(validator)
+		if height == 0 {
+			bc = append(bc, b)
+		} else if !bc.AddBlock(&b) {
+			t.Fatalf("block %d rejected", height)
+		}
+	}
+	if err := bc.VerifyChain(0, -1); err != nil {
+		t.Fatal(err)
+	}
+
+	outOfTurn := MakeProofOfAuthority(signers, bob)
+	b := Block{Index: uint64(len(bc))}
+	if outOfTurn.Seal(&bc, &b) == nil {
+		t.Fatal("signer sealed out of turn")
+	}
+	if err := b.SignBlock(bob); err != nil {
+		t.Fatal(err)
+	}
+	if validator.VerifySeal(&bc, &b) {
+		t.Fatal("block signed out of turn accepted")
+	}
+
+	b = Block{Index: uint64(len(bc))}
+	if err := b.SignBlock(outsider); err != nil {
+		t.Fatal(err)
+	}
+	if validator.VerifySeal(&bc, &b) {
+		t.Fatal("block signed by someone outside the signer set accepted")
+	}
+	if validator.Seal(&bc, &b) == nil {
+		t.Fatal("a node without a key sealed a block")
+	}
+
+	inTurn := MakeProofOfAuthority(signers, alice)
+	b = Block{Index: uint64(len(bc))}
+	if err := inTurn.Seal(&bc, &b); err != nil {
+		t.Fatal(err)
+	}
+	b.Timestamp++
+	if validator.VerifySeal(&bc, &b) {
+		t.Fatal("block changed after signing accepted")
+	}
+
+	longer := append(append(Blockchain{}, bc...), Block{})
+	if !validator.ForkChoice(bc, longer) || validator.ForkChoice(longer, bc) {
+		t.Fatal("the longest chain doesn't win")
+	}
+}
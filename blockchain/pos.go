@@ -0,0 +1,395 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sort"
+	"strconv"
+)
+
+const (
+	// UnbondingDelay - Number of blocks unstaked coins stay locked
+	// for before they become spendable again. The stake can
+	// still be slashed during that time
+	UnbondingDelay = 100
+)
+
+// Validator - A public key together with the stake it has bonded
+type Validator struct {
+	PubKey *ecdsa.PublicKey `json:"PubKey"`
+	Stake  float64          `json:"Stake"`
+}
+
+// SlashingEvidence - Two different blocks at the same height that
+// were signed by the same proposer. Goes in the Data of a
+// TXSlash transaction
+type SlashingEvidence struct {
+	BlockA Block `json:"BlockA"`
+	BlockB Block `json:"BlockB"`
+}
+
+// unbondingEntry - Stake that was unstaked at a certain height
+type unbondingEntry struct {
+	Amount float64
+	Height int
+}
+
+// stakeLedger - The staking state of a single public key
+type stakeLedger struct {
+	Bonded    float64
+	Unbonding []unbondingEntry
+}
+
+/************************************
+ * Slashing evidence
+************************************/
+
+// MakeSlashingEvidence - SlashingEvidence constructor
+func MakeSlashingEvidence(a *Block, b *Block) SlashingEvidence {
+	return SlashingEvidence{BlockA: *a, BlockB: *b}
+}
+
+// Encode - Serializes the evidence so it can be put into
+// the Data of a TXSlash transaction
+func (e *SlashingEvidence) Encode() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// DecodeSlashingEvidence - Deserializes the Data of a TXSlash transaction
+func DecodeSlashingEvidence(data []byte) (*SlashingEvidence, error) {
+	var e SlashingEvidence
+	err := json.Unmarshal(data, &e)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// SlashingEvidenceIsValid - Returns true if both blocks are at the
+// same height, are different, and carry valid signatures
+// from the same signer. The signatures cover the index, so
+// a block signed at one height can't be passed off as
+// one signed at another
+func (e *SlashingEvidence) SlashingEvidenceIsValid() bool {
+	if e.BlockA.Index != e.BlockB.Index {
+		return false
+	}
+	if !e.BlockA.BlockSignatureIsValid() || !e.BlockB.BlockSignatureIsValid() {
+		return false
+	}
+	if e.BlockA.XSigner.Cmp(e.BlockB.XSigner) != 0 || e.BlockA.YSigner.Cmp(e.BlockB.YSigner) != 0 {
+		return false
+	}
+	return bytes.Compare(e.BlockA.SealHash(), e.BlockB.SealHash()) != 0
+}
+
+// Offender - Returns the public key that signed both blocks
+func (e *SlashingEvidence) Offender() *ecdsa.PublicKey {
+	return &ecdsa.PublicKey{Curve: elliptic.P384(), X: e.BlockA.XSigner, Y: e.BlockA.YSigner}
+}
+
+/************************************
+ * Stake accounting
+************************************/
+
+// blockLimit - Turns the index parameter used by the balance
+// functions into the number of blocks to go through
+func (bc *Blockchain) blockLimit(index int64) int {
+	if index > 0 && index < int64(len(*bc)) {
+		return int(index)
+	}
+	return len(*bc)
+}
+
+// apply - Applies a staking transaction in the block at the given
+// height to the ledger of a public key
+func (ledger *stakeLedger) apply(tx *Transaction, pubKey *ecdsa.PublicKey, height int) {
+	switch tx.Type {
+	case TXStake:
+		if tx.isInput(pubKey) {
+			ledger.Bonded += tx.Amount
+		}
+	case TXUnstake:
+		if tx.isInput(pubKey) {
+			ledger.Bonded -= tx.Amount
+			ledger.Unbonding = append(ledger.Unbonding, unbondingEntry{Amount: tx.Amount, Height: height})
+		}
+	case TXSlash:
+		evidence, err := DecodeSlashingEvidence(tx.Data)
+		if err != nil || !evidence.SlashingEvidenceIsValid() {
+			return
+		}
+		offender := evidence.Offender()
+		if offender.X.Cmp(pubKey.X) != 0 || offender.Y.Cmp(pubKey.Y) != 0 {
+			return
+		}
+
+		// Burn the bonded stake and whatever is still unbonding
+		ledger.Bonded = 0
+		var matured []unbondingEntry
+		for _, entry := range ledger.Unbonding {
+			if height-entry.Height >= UnbondingDelay {
+				matured = append(matured, entry)
+			}
+		}
+		ledger.Unbonding = matured
+	}
+}
+
+// stakeLedgerOf - Replays the staking transactions of the first
+// limit blocks for a public key
+func (bc *Blockchain) stakeLedgerOf(pubKey *ecdsa.PublicKey, limit int) stakeLedger {
+	var ledger stakeLedger
+	for i := 0; i < limit; i++ {
+		for j := range (*bc)[i].TXs {
+			ledger.apply(&(*bc)[i].TXs[j], pubKey, i)
+		}
+	}
+
+	return ledger
+}
+
+// unstakeIsValid - Checks that the input of a TXUnstake has at least
+// Amount bonded, counting the staking transactions in txpool
+func (t *Transaction) unstakeIsValid(bc *Blockchain, txpool []Transaction) bool {
+	pubKey := &ecdsa.PublicKey{Curve: elliptic.P384(), X: t.XInput, Y: t.YInput}
+	ledger := bc.stakeLedgerOf(pubKey, len(*bc))
+	for i := range txpool {
+		ledger.apply(&txpool[i], pubKey, len(*bc))
+	}
+	return t.Amount > 0 && t.Amount <= ledger.Bonded
+}
+
+// slashingSlot - Identifies the slot the evidence is about: the
+// offender and the height. Every slot can only be slashed once
+func (e *SlashingEvidence) slashingSlot() string {
+	return accountKey(e.BlockA.XSigner, e.BlockA.YSigner) + "/" + strconv.FormatUint(e.BlockA.Index, Base)
+}
+
+// slashIsValid - Checks a TXSlash. Besides being valid on its own,
+// the evidence has to be about this chain: both blocks have to
+// extend the block below their height and be sealed the way the
+// consensus engine expects there, which means the offender really
+// was the proposer of the slot. Nobody can have been slashed for
+// the slot yet, on the blockchain or in txpool, so the evidence
+// can't be replayed to burn stake bonded later on
+func (t *Transaction) slashIsValid(bc *Blockchain, txpool []Transaction) bool {
+	evidence, err := DecodeSlashingEvidence(t.Data)
+	if err != nil || !evidence.SlashingEvidenceIsValid() {
+		return false
+	}
+	height := evidence.BlockA.Index
+	if height == 0 || height > uint64(len(*bc)) {
+		return false
+	}
+	prefix := (*bc)[:height]
+	for _, b := range []*Block{&evidence.BlockA, &evidence.BlockB} {
+		if bytes.Compare(b.PrevHash, prefix[height-1].Hash) != 0 || !consensus.VerifySeal(&prefix, b) {
+			return false
+		}
+	}
+
+	slot := evidence.slashingSlot()
+	spent := func(tx *Transaction) bool {
+		if tx.Type != TXSlash {
+			return false
+		}
+		other, err := DecodeSlashingEvidence(tx.Data)
+		return err == nil && other.slashingSlot() == slot
+	}
+	for i := range *bc {
+		for j := range (*bc)[i].TXs {
+			if spent(&(*bc)[i].TXs[j]) {
+				return false
+			}
+		}
+	}
+	for i := range txpool {
+		if spent(&txpool[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// CalcStakeOnBC - Returns the stake a public key has bonded. The
+// index parameter works the same way as in CalcAccountBalanceOnBC
+func (bc *Blockchain) CalcStakeOnBC(pubKey *ecdsa.PublicKey, index int64) float64 {
+	return bc.stakeLedgerOf(pubKey, bc.blockLimit(index)).Bonded
+}
+
+// CalcUnbondedOnBC - Returns the amount of unstaked coins of a public
+// key that have gone through the whole unbonding delay. The index
+// parameter works the same way as in CalcAccountBalanceOnBC
+func (bc *Blockchain) CalcUnbondedOnBC(pubKey *ecdsa.PublicKey, index int64) float64 {
+	limit := bc.blockLimit(index)
+	ledger := bc.stakeLedgerOf(pubKey, limit)
+
+	var total float64 = 0
+	for _, entry := range ledger.Unbonding {
+		if limit-1-entry.Height >= UnbondingDelay {
+			total += entry.Amount
+		}
+	}
+	return total
+}
+
+// Validators - Returns every public key with bonded stake, sorted so
+// that every node gets the same order. The index parameter works
+// the same way as in CalcAccountBalanceOnBC
+func (bc *Blockchain) Validators(index int64) []Validator {
+	limit := bc.blockLimit(index)
+
+	// Find everyone who has ever staked
+	seen := make(map[string]bool)
+	var stakers []*ecdsa.PublicKey
+	for i := 0; i < limit; i++ {
+		for _, tx := range (*bc)[i].TXs {
+			if tx.Type != TXStake {
+				continue
+			}
+			key := tx.XInput.String() + "," + tx.YInput.String()
+			if !seen[key] {
+				seen[key] = true
+				stakers = append(stakers, &ecdsa.PublicKey{Curve: elliptic.P384(), X: tx.XInput, Y: tx.YInput})
+			}
+		}
+	}
+
+	var validators []Validator
+	for _, staker := range stakers {
+		stake := bc.stakeLedgerOf(staker, limit).Bonded
+		if stake > 0 {
+			validators = append(validators, Validator{PubKey: staker, Stake: stake})
+		}
+	}
+
+	sort.Slice(validators, func(i, j int) bool {
+		if c := validators[i].PubKey.X.Cmp(validators[j].PubKey.X); c != 0 {
+			return c < 0
+		}
+		return validators[i].PubKey.Y.Cmp(validators[j].PubKey.Y) < 0
+	})
+
+	return validators
+}
+
+// proposerSeed - Returns a number in [0, 1) derived from the previous
+// block hash and the height, which every node agrees on
+func (bc *Blockchain) proposerSeed(height uint64) float64 {
+	var prevHash []byte
+	if len(*bc) > 0 {
+		prevHash = (*bc)[len(*bc)-1].Hash
+	}
+	heightBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(heightBytes, height)
+
+	seed := sha256.Sum256(append(append([]byte{}, prevHash...), heightBytes...))
+	// Keep 53 bits so the number fits exactly into a float64
+	return float64(binary.BigEndian.Uint64(seed[:8])>>11) / float64(uint64(1)<<53)
+}
+
+/************************************
+ * Proof of stake
+************************************/
+
+// ProofOfStake - A consensus engine where the proposer of every
+// slot is picked pseudorandomly, weighted by bonded stake, and
+// signs the block instead of mining it. Until anyone has staked,
+// the proposer is picked uniformly from the genesis validators
+type ProofOfStake struct {
+	GenesisValidators []*ecdsa.PublicKey
+
+	// Key - The private key this node proposes blocks with. Leave
+	// it nil if this node only validates
+	Key *ecdsa.PrivateKey
+}
+
+// MakeProofOfStake - ProofOfStake constructor
+func MakeProofOfStake(genesis []*ecdsa.PublicKey, key *ecdsa.PrivateKey) *ProofOfStake {
+	return &ProofOfStake{GenesisValidators: genesis, Key: key}
+}
+
+// SelectProposer - Returns the proposer of the block at the given
+// height on top of bc. Returns nil if there is nobody to pick from
+func (pos *ProofOfStake) SelectProposer(bc *Blockchain, height uint64) *ecdsa.PublicKey {
+	seed := bc.proposerSeed(height)
+
+	validators := bc.Validators(-1)
+	var total float64 = 0
+	for _, v := range validators {
+		total += v.Stake
+	}
+
+	if total == 0 {
+		if len(pos.GenesisValidators) == 0 {
+			return nil
+		}
+		return pos.GenesisValidators[int(seed*float64(len(pos.GenesisValidators)))]
+	}
+
+	target := seed * total
+	var cumulative float64 = 0
+	for _, v := range validators {
+		cumulative += v.Stake
+		if target < cumulative {
+			return v.PubKey
+		}
+	}
+
+	// Rounding can leave the target just past the last validator
+	return validators[len(validators)-1].PubKey
+}
+
+// Seal - Signs the block with this node's key. Fails if this node
+// has no key or wasn't picked as the proposer
+func (pos *ProofOfStake) Seal(bc *Blockchain, b *Block) error {
+	if pos.Key == nil {
+		return errors.New("ProofOfStake: no signing key configured")
+	}
+	proposer := pos.SelectProposer(bc, b.Index)
+	if proposer == nil {
+		return errors.New("ProofOfStake: no validators to pick a proposer from")
+	}
+	if proposer.X.Cmp(pos.Key.X) != 0 || proposer.Y.Cmp(pos.Key.Y) != 0 {
+		return errors.New("ProofOfStake: this node isn't the proposer of the slot")
+	}
+	return b.SignBlock(pos.Key)
+}
+
+// VerifySeal - Checks that the block sits at the next height of the
+// chain and was signed by the proposer picked for its slot
+func (pos *ProofOfStake) VerifySeal(bc *Blockchain, b *Block) bool {
+	if b.Index != uint64(len(*bc)) {
+		return false
+	}
+	if !b.BlockSignatureIsValid() {
+		return false
+	}
+	proposer := pos.SelectProposer(bc, b.Index)
+	if proposer == nil {
+		return false
+	}
+	return proposer.X.Cmp(b.XSigner) == 0 && proposer.Y.Cmp(b.YSigner) == 0
+}
+
+// CalcDifficulty - Nothing is mined, so there is no difficulty
+func (pos *ProofOfStake) CalcDifficulty(bc *Blockchain) uint32 {
+	return 0
+}
+
+// BlockWeight - Every block counts the same
+func (pos *ProofOfStake) BlockWeight(b *Block) *big.Int {
+	return big.NewInt(1)
+}
+
+// ForkChoice - The longest chain wins
+func (pos *ProofOfStake) ForkChoice(current Blockchain, candidate Blockchain) bool {
+	return len(candidate) > len(current)
+}
@@ -0,0 +1,244 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"testing"
+)
+
+// signedBlock - Returns a block at the given index signed by key
+func signedBlock(t *testing.T, key *ecdsa.PrivateKey, index uint64, timestamp uint64) Block {
+	t.Helper()
+	b := Block{Index: index, Timestamp: timestamp, PrevHash: []byte{byte(index)}}
+	if err := b.SignBlock(key); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSlashingEvidenceRejectsRelabelledBlocks(t *testing.T) {
+	key := testKey(t)
+
+	// Two honest blocks at heights 5 and 6, with the second one
+	// relabelled as height 5 and rehashed
+	a := signedBlock(t, key, 5, 1000)
+	b := signedBlock(t, key, 6, 1001)
+	b.Index = 5
+	b.Hash = b.HashBlock()
+
+	evidence := MakeSlashingEvidence(&a, &b)
+	if evidence.SlashingEvidenceIsValid() {
+		t.Fatal("relabelled block accepted as slashing evidence")
+	}
+}
+
+func TestSlashingEvidence(t *testing.T) {
+	key := testKey(t)
+	other := testKey(t)
+
+	a := signedBlock(t, key, 5, 1000)
+	b := signedBlock(t, key, 5, 1001)
+	evidence := MakeSlashingEvidence(&a, &b)
+	if !evidence.SlashingEvidenceIsValid() {
+		t.Fatal("two blocks signed at the same height aren't evidence")
+	}
+	if offender := evidence.Offender(); offender.X.Cmp(key.X) != 0 || offender.Y.Cmp(key.Y) != 0 {
+		t.Fatal("wrong offender")
+	}
+
+	data, err := evidence.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeSlashingEvidence(data)
+	if err != nil || !decoded.SlashingEvidenceIsValid() {
+		t.Fatal("evidence doesn't survive encoding")
+	}
+
+	same := MakeSlashingEvidence(&a, &a)
+	if same.SlashingEvidenceIsValid() {
+		t.Fatal("the same block twice is not evidence")
+	}
+	c := signedBlock(t, other, 5, 1001)
+	mixed := MakeSlashingEvidence(&a, &c)
+	if mixed.SlashingEvidenceIsValid() {
+		t.Fatal("blocks of different signers are not evidence")
+	}
+	tampered := b
+	tampered.Timestamp++
+	tampered.Hash = tampered.HashBlock()
+	forged := MakeSlashingEvidence(&a, &tampered)
+	if forged.SlashingEvidenceIsValid() {
+		t.Fatal("block with a broken signature accepted")
+	}
+}
+
+// stakingTX - Returns a signed staking transaction from key
+func stakingTX(t *testing.T, txType uint32, key *ecdsa.PrivateKey, amount float64, data []byte) Transaction {
+	t.Helper()
+	tx := transfer(t, key, &key.PublicKey, amount)
+	tx.Type = txType
+	tx.Data = data
+	signTX(t, &tx, key)
+	return tx
+}
+
+// equivocation - Returns slashing evidence of key signing two
+// different blocks on top of the blockchain
+func equivocation(t *testing.T, bc *Blockchain, key *ecdsa.PrivateKey) SlashingEvidence {
+	t.Helper()
+	a := nextBlock(t, bc)
+	b := a
+	b.Timestamp++
+	if err := b.SignBlock(key); err != nil {
+		t.Fatal(err)
+	}
+	return MakeSlashingEvidence(&a, &b)
+}
+
+func TestStakeUnbondingAndSlashing(t *testing.T) {
+	defer SetConsensusEngine(CurrentConsensusEngine())
+	key := testKey(t)
+	alice := testKey(t)
+	pub := &key.PublicKey
+	SetConsensusEngine(MakeProofOfStake([]*ecdsa.PublicKey{pub}, key))
+
+	treasury := testKey(t)
+	bc := genesisChain(t, transfer(t, treasury, pub, 10), transfer(t, treasury, &alice.PublicKey, 10))
+	add := func(txs ...Transaction) bool {
+		b := nextBlock(t, &bc, txs...)
+		return bc.AddBlock(&b)
+	}
+
+	// Staking everything still leaves the stake to unstake
+	if !add(stakingTX(t, TXStake, key, 10, nil)) {
+		t.Fatal("stake rejected")
+	}
+	if add(stakingTX(t, TXUnstake, key, 11, nil)) {
+		t.Fatal("unstake of more than the stake accepted")
+	}
+	if add(stakingTX(t, TXUnstake, key, 6, nil), stakingTX(t, TXUnstake, key, 5, nil)) {
+		t.Fatal("unstakes adding up to more than the stake accepted in one block")
+	}
+	pool := MakeTXPool()
+	if err := pool.AddTransaction(&bc, stakingTX(t, TXUnstake, key, 6, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if pool.AddTransaction(&bc, stakingTX(t, TXUnstake, key, 5, nil)) == nil {
+		t.Fatal("unstakes adding up to more than the stake accepted into the pool")
+	}
+	if !add(stakingTX(t, TXUnstake, key, 4, nil)) {
+		t.Fatal("unstake rejected")
+	}
+	if stake := bc.CalcStakeOnBC(pub, -1); stake != 6 {
+		t.Fatalf("stake = %v, want 6", stake)
+	}
+	if validators := bc.Validators(-1); len(validators) != 1 || validators[0].Stake != 6 {
+		t.Fatalf("validators = %v", validators)
+	}
+
+	bc = extend(t, bc, UnbondingDelay)
+	if unbonded := bc.CalcUnbondedOnBC(pub, -1); unbonded != 4 {
+		t.Fatalf("unbonded after the delay = %v, want 4", unbonded)
+	}
+	if !add(stakingTX(t, TXUnstake, key, 1, nil)) {
+		t.Fatal("unstake rejected")
+	}
+
+	// Only blocks sealed on this chain by the proposer of their
+	// slot are evidence
+	outsider := testKey(t)
+	invalid := map[string]SlashingEvidence{
+		"blocks off the chain": func() SlashingEvidence {
+			a := signedBlock(t, key, 5, 1000)
+			b := signedBlock(t, key, 5, 1001)
+			return MakeSlashingEvidence(&a, &b)
+		}(),
+		"blocks of someone other than the proposer": func() SlashingEvidence {
+			e := equivocation(t, &bc, key)
+			e.BlockA.SignBlock(outsider)
+			e.BlockB.SignBlock(outsider)
+			return e
+		}(),
+	}
+	for name, e := range invalid {
+		data, _ := e.Encode()
+		if add(stakingTX(t, TXSlash, alice, 0, data)) {
+			t.Fatalf("slash with %s accepted", name)
+		}
+	}
+
+	evidence := equivocation(t, &bc, key)
+	honest := evidence.BlockA
+	if !bc.AddBlock(&honest) {
+		t.Fatal("honest block rejected")
+	}
+	data, _ := evidence.Encode()
+	slash := stakingTX(t, TXSlash, alice, 0, data)
+	second := slash
+	second.Timestamp = 1
+	signTX(t, &second, alice)
+	if add(slash, second) {
+		t.Fatal("two slashes for the same slot accepted in one block")
+	}
+	if !add(slash) {
+		t.Fatal("slash rejected")
+	}
+
+	// Slashing burns the bonded stake and what's still unbonding,
+	// but not what already made it through
+	if stake := bc.CalcStakeOnBC(pub, -1); stake != 0 {
+		t.Fatalf("stake after slashing = %v", stake)
+	}
+	if unbonded := bc.CalcUnbondedOnBC(pub, -1); unbonded != 4 {
+		t.Fatalf("unbonded after slashing = %v, want 4", unbonded)
+	}
+
+	// The evidence can't burn stake bonded afterwards
+	if !add(transfer(t, alice, pub, 5)) || !add(stakingTX(t, TXStake, key, 5, nil)) {
+		t.Fatal("stake after slashing rejected")
+	}
+	swapped := MakeSlashingEvidence(&evidence.BlockB, &evidence.BlockA)
+	for _, e := range []SlashingEvidence{evidence, swapped} {
+		data, _ := e.Encode()
+		if add(stakingTX(t, TXSlash, alice, 0, data)) {
+			t.Fatal("replayed slashing evidence accepted")
+		}
+	}
+	if stake := bc.CalcStakeOnBC(pub, -1); stake != 5 {
+		t.Fatalf("stake after the replays = %v, want 5", stake)
+	}
+}
+
+func TestProofOfStakeSeal(t *testing.T) {
+	key := testKey(t)
+	other := testKey(t)
+	pos := MakeProofOfStake([]*ecdsa.PublicKey{&key.PublicKey}, key)
+
+	bc := Blockchain{{Index: 0, Hash: []byte("genesis")}}
+	b := Block{Timestamp: 1000}
+	b.Index = 1
+	if err := pos.Seal(&bc, &b); err != nil {
+		t.Fatal(err)
+	}
+	if !pos.VerifySeal(&bc, &b) {
+		t.Fatal("sealed block rejected")
+	}
+
+	wrongHeight := b
+	wrongHeight.Index = 2
+	if pos.VerifySeal(&bc, &wrongHeight) {
+		t.Fatal("block at the wrong height accepted")
+	}
+
+	notProposer := MakeProofOfStake([]*ecdsa.PublicKey{&key.PublicKey}, other)
+	c := Block{Index: 1}
+	if notProposer.Seal(&bc, &c) == nil {
+		t.Fatal("a key that isn't the proposer sealed a block")
+	}
+	if err := c.SignBlock(other); err != nil {
+		t.Fatal(err)
+	}
+	if pos.VerifySeal(&bc, &c) {
+		t.Fatal("block signed by someone other than the proposer accepted")
+	}
+}
@@ -0,0 +1,201 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+const (
+	// HashFormatV1 - The original block hash: a SHA 256 over the
+	// timestamp, difficulty and nonce and every transaction, written
+	// out one after the other. It doesn't cover the version, index,
+	// previous hash, roots or signer of the block, and any
+	// transaction field added since the original format. Only
+	// chains whose parameters schedule it still use it
+	HashFormatV1 = 0
+
+	// HashFormatV2 - Block hashes are a SHA 256 over a fixed-width,
+	// length-prefixed encoding of the header, which covers every
+	// header field and the transactions through the Merkle root.
+	// Two different headers can't encode the same way
+	HashFormatV2 = 1
+)
+
+// RuleSet - Consensus rules that take over from the previous rule set
+// at a height agreed on in advance, or once a version bits deployment
+// activates. Every block gets checked under the rule set active at
+// its height, so blocks from before a change stay valid. Limits
+// of zero mean no limit
+type RuleSet struct {
+	Name             string `json:"Name"`
+	ActivationHeight uint64 `json:"ActivationHeight"`
+
+	// Deployment - If set, the rule set only takes over once the
+	// deployment of that name is active, and no earlier than
+	// ActivationHeight. Whether it applies depends on the chain,
+	// so it can't change the hash format: blocks keep the hash
+	// format of the scheduled rule sets
+	Deployment string `json:"Deployment"`
+
+	// HashFormat - How blocks get hashed. One of HashFormatV1
+	// or HashFormatV2
+	HashFormat int `json:"HashFormat"`
+
+	// MaxBlockTXs - Maximum number of transactions in a block
+	MaxBlockTXs int `json:"MaxBlockTXs"`
+
+	// MaxTXDataSize - Maximum length of the Data of a
+	// transaction (in bytes)
+	MaxTXDataSize int `json:"MaxTXDataSize"`
+}
+
+// RuleSetAt - Returns the scheduled rule set of the chain parameters
+// that is active at the given height: the one with the highest
+// activation height at or below it. Without any, the original rules
+// apply. Rule sets gated on a deployment are left out, since
+// whether they apply depends on the chain: see ActiveRuleSet
+func RuleSetAt(height uint64) RuleSet {
+	rules := RuleSet{Name: "original", HashFormat: HashFormatV1}
+	found := false
+	for _, r := range params.RuleSets {
+		if r.Deployment != "" {
+			continue
+		}
+		if r.ActivationHeight <= height && (!found || r.ActivationHeight >= rules.ActivationHeight) {
+			rules = r
+			found = true
+		}
+	}
+	return rules
+}
+
+// ActiveRuleSet - Returns the rule set that applies to the block at
+// the given height on top of the blockchain: the scheduled one from
+// RuleSetAt, unless a rule set gated on a deployment that is active
+// by then took over at or after it. The hash format always comes
+// from RuleSetAt
+func (bc *Blockchain) ActiveRuleSet(height uint64) RuleSet {
+	rules := RuleSetAt(height)
+	hashFormat := rules.HashFormat
+	for _, r := range params.RuleSets {
+		if r.Deployment == "" || r.ActivationHeight > height || r.ActivationHeight < rules.ActivationHeight {
+			continue
+		}
+		if bc.DeploymentIsActive(r.Deployment, height) {
+			rules = r
+		}
+	}
+	rules.HashFormat = hashFormat
+	return rules
+}
+
+// BlockSizeIsValid - Checks the number of transactions in a block
+// against its rule set
+func (rules *RuleSet) BlockSizeIsValid(b *Block) bool {
+	return rules.MaxBlockTXs == 0 || len(b.TXs) <= rules.MaxBlockTXs
+}
+
+// TransactionSizeIsValid - Checks the size of a transaction
+// against a rule set
+func (rules *RuleSet) TransactionSizeIsValid(t *Transaction) bool {
+	return rules.MaxTXDataSize == 0 || len(t.Data) <= rules.MaxTXDataSize
+}
+
+// hashBlockV1 - HashFormatV1 of a block. This has to stay byte for
+// byte the same as the original HashBlock, or the hashes of every
+// block from before the rule sets stop matching
+func (b *Block) hashBlockV1() []byte {
+	// here is the buffer that stores the data temporarily
+	var buff string
+
+	// write the block headers to the buffer
+	buff += strconv.FormatUint(b.Timestamp, Base)
+	buff += strconv.FormatUint(uint64(b.Difficulty), Base)
+	buff += string(b.Nonce)
+
+	// write all the transactions to the buffer
+	for _, tx := range b.TXs {
+		// write the version
+		buff += strconv.FormatUint(uint64(tx.Version), Base)
+
+		// write the input, output, and amount
+		buff += tx.XInput.String()
+		buff += tx.YInput.String()
+		buff += tx.XOutput.String()
+		buff += tx.YOutput.String()
+		buff += strconv.FormatFloat(tx.Amount, 'f', -1, 64)
+
+		// write the timestamp and extra data
+		buff += strconv.FormatUint(tx.Timestamp, Base)
+		buff += string(tx.Data)
+
+		// write the signature
+		buff += tx.RSignature.String()
+		buff += tx.SSignature.String()
+	}
+
+	hash := sha256.Sum256([]byte(buff))
+	return hash[:]
+}
+
+// hashHeaderV2 - HashFormatV2 of a header
+func (h *BlockHeader) hashHeaderV2() []byte {
+	var buff hashBuffer
+	buff.putUint(uint64(h.Version))
+	buff.putUint(h.Index)
+	buff.putBytes(h.PrevHash)
+	buff.putUint(h.Timestamp)
+	buff.putUint(uint64(h.Difficulty))
+	buff.putBytes(h.Nonce)
+	buff.putBytes(h.StateRoot)
+	buff.putBytes(h.MerkleRoot)
+	if h.XSigner != nil && h.YSigner != nil {
+		buff.putBig(h.XSigner)
+		buff.putBig(h.YSigner)
+	} else {
+		buff.putBytes(nil)
+		buff.putBytes(nil)
+	}
+
+	hash := sha256.Sum256(buff)
+	return hash[:]
+}
+
+// hashBuffer - The input of a hash, written out as fixed-width
+// integers and length-prefixed byte strings, so that no two
+// different sets of fields encode to the same bytes
+type hashBuffer []byte
+
+// putUint - Writes an unsigned integer as 8 big endian bytes
+func (buff *hashBuffer) putUint(v uint64) {
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], v)
+	*buff = append(*buff, n[:]...)
+}
+
+// putBytes - Writes a byte string prefixed with its length
+func (buff *hashBuffer) putBytes(b []byte) {
+	buff.putUint(uint64(len(b)))
+	*buff = append(*buff, b...)
+}
+
+// putFloat - Writes the bits of a float
+func (buff *hashBuffer) putFloat(f float64) {
+	buff.putUint(math.Float64bits(f))
+}
+
+// putBig - Writes a big integer as its sign and its magnitude.
+// A nil integer is written the same way as zero
+func (buff *hashBuffer) putBig(n *big.Int) {
+	if n == nil {
+		buff.putBytes(nil)
+		return
+	}
+	if n.Sign() < 0 {
+		*buff = append(*buff, 1)
+	}
+	buff.putBytes(n.Bytes())
+}
@@ -0,0 +1,148 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+// goldenBlock - A block whose HashFormatV1 hash was computed with
+// the original HashBlock
+func goldenBlock() Block {
+	return Block{
+		Index:      3,
+		Timestamp:  1700000000,
+		Difficulty: 1,
+		Nonce:      []byte("nonce"),
+		TXs: []Transaction{{
+			Version:    1,
+			XInput:     big.NewInt(11),
+			YInput:     big.NewInt(12),
+			XOutput:    big.NewInt(13),
+			YOutput:    big.NewInt(14),
+			Amount:     2.5,
+			Timestamp:  1700000001,
+			Data:       []byte("data"),
+			RSignature: big.NewInt(15),
+			SSignature: big.NewInt(16),
+		}},
+	}
+}
+
+func TestHashFormatV1MatchesOriginalHash(t *testing.T) {
+	defer SetChainParams(ActiveChainParams())
+	p := DefaultChainParams()
+	p.RuleSets = []RuleSet{{Name: "original", HashFormat: HashFormatV1}}
+	SetChainParams(p)
+
+	b := goldenBlock()
+	want := "1b98f0b2c9d114bb13fcc3e0bbb7d02fe13bd66b47485da739167c3e096b3828"
+	if got := hex.EncodeToString(b.HashBlock()); got != want {
+		t.Fatalf("V1 hash = %s, want %s", got, want)
+	}
+
+	// None of the fields added since the original format change it
+	b.Version = VersionBitsTopBits
+	b.PrevHash = []byte("prev")
+	b.StateRoot = []byte("state")
+	b.MerkleRoot = []byte("merkle")
+	b.XSigner = big.NewInt(1)
+	b.YSigner = big.NewInt(2)
+	if got := hex.EncodeToString(b.HashBlock()); got != want {
+		t.Fatalf("V1 hash changed with the new header fields: %s", got)
+	}
+
+	header := b.Header()
+	if header.HashHeader() != nil || header.HeaderHashIsValid() {
+		t.Fatal("V1 headers can't be hashed without their transactions")
+	}
+}
+
+func TestHashFormatV2CoversHeader(t *testing.T) {
+	// The main chain hashes every block with HashFormatV2
+	defer SetChainParams(ActiveChainParams())
+	SetChainParams(DefaultChainParams())
+	if RuleSetAt(0).HashFormat != HashFormatV2 {
+		t.Fatal("default chain parameters don't use HashFormatV2")
+	}
+
+	b := goldenBlock()
+	b.MerkleRoot = CalcMerkleRoot(b.TXs)
+	hash := b.HashBlock()
+	header := b.Header()
+	if bytes.Compare(header.HashHeader(), hash) != 0 {
+		t.Fatal("V2 block hash and header hash differ")
+	}
+
+	changes := []func(b *Block){
+		func(b *Block) { b.Index++ },
+		func(b *Block) { b.Version = VersionBitsTopBits },
+		func(b *Block) { b.PrevHash = []byte("prev") },
+		func(b *Block) { b.StateRoot = []byte("state") },
+		func(b *Block) { b.MerkleRoot = nil },
+		func(b *Block) { b.XSigner, b.YSigner = big.NewInt(1), big.NewInt(2) },
+	}
+	for i, change := range changes {
+		c := goldenBlock()
+		c.MerkleRoot = CalcMerkleRoot(c.TXs)
+		change(&c)
+		if bytes.Compare(c.HashBlock(), hash) == 0 {
+			t.Fatalf("change %d doesn't change the V2 hash", i)
+		}
+	}
+
+	// Transaction fields are covered through the Merkle root
+	txChanges := []func(tx *Transaction){
+		func(tx *Transaction) { tx.Type = TXCall },
+		func(tx *Transaction) { tx.LockTime = 1 },
+		func(tx *Transaction) { tx.GasLimit = 1 },
+		func(tx *Transaction) { tx.KeyType = KeyEd25519 },
+		func(tx *Transaction) { tx.Recipient = []byte("recipient") },
+		func(tx *Transaction) { tx.Signatures = []TXSignature{{}} },
+		func(tx *Transaction) { tx.UnlockingScript = []byte{OpTrue} },
+	}
+	for i, change := range txChanges {
+		c := goldenBlock()
+		change(&c.TXs[0])
+		c.MerkleRoot = CalcMerkleRoot(c.TXs)
+		if bytes.Compare(c.HashBlock(), hash) == 0 {
+			t.Fatalf("transaction change %d doesn't change the V2 hash", i)
+		}
+	}
+}
+
+func TestRuleSetAt(t *testing.T) {
+	defer SetChainParams(ActiveChainParams())
+	p := DefaultChainParams()
+	p.RuleSets = []RuleSet{
+		{Name: "b", ActivationHeight: 100, HashFormat: HashFormatV2, MaxBlockTXs: 2},
+		{Name: "a", ActivationHeight: 0},
+		{Name: "c", ActivationHeight: 200, HashFormat: HashFormatV2, MaxTXDataSize: 4},
+	}
+	SetChainParams(p)
+
+	tests := []struct {
+		height uint64
+		name   string
+	}{{0, "a"}, {99, "a"}, {100, "b"}, {199, "b"}, {200, "c"}, {1 << 40, "c"}}
+	for _, test := range tests {
+		if rules := RuleSetAt(test.height); rules.Name != test.name {
+			t.Fatalf("RuleSetAt(%d) = %s, want %s", test.height, rules.Name, test.name)
+		}
+	}
+
+	rules := RuleSetAt(100)
+	if !rules.BlockSizeIsValid(&Block{TXs: make([]Transaction, 2)}) || rules.BlockSizeIsValid(&Block{TXs: make([]Transaction, 3)}) {
+		t.Fatal("MaxBlockTXs not enforced")
+	}
+	rules = RuleSetAt(200)
+	if !rules.TransactionSizeIsValid(&Transaction{Data: []byte("1234")}) || rules.TransactionSizeIsValid(&Transaction{Data: []byte("12345")}) {
+		t.Fatal("MaxTXDataSize not enforced")
+	}
+
+	SetChainParams(ChainParams{})
+	if rules := RuleSetAt(5); rules.Name != "original" || rules.HashFormat != HashFormatV1 {
+		t.Fatal("without rule sets the original rules apply")
+	}
+}
@@ -0,0 +1,344 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+)
+
+// Script opcodes. Opcodes 0x01 to 0x4b push that many of the
+// bytes that follow them onto the stack
+const (
+	OpFalse               = 0x00
+	OpPushData1           = 0x4c
+	OpPushData2           = 0x4d
+	OpTrue                = 0x51
+	OpVerify              = 0x69
+	OpDrop                = 0x75
+	OpDup                 = 0x76
+	OpSwap                = 0x7c
+	OpEqual               = 0x87
+	OpEqualVerify         = 0x88
+	OpNot                 = 0x91
+	OpBoolAnd             = 0x9a
+	OpBoolOr              = 0x9b
+	OpSHA256              = 0xa8
+	OpCheckSig            = 0xac
+	OpCheckLockTimeVerify = 0xb1
+)
+
+const (
+	// MaxScriptSize - Maximum length of a single script (in bytes)
+	MaxScriptSize = 10000
+
+	// MaxScriptOps - Maximum number of non-push opcodes the
+	// unlocking and locking scripts can run together
+	MaxScriptOps = 201
+
+	// MaxStackSize - Maximum number of elements on the stack
+	MaxStackSize = 1000
+
+	// MaxScriptElementSize - Maximum length of a single
+	// stack element (in bytes)
+	MaxScriptElementSize = 520
+
+	// scriptSignatureLen - Length of a P-384 signature in a script:
+	// r and s, 48 bytes each
+	scriptSignatureLen = 96
+)
+
+// ScriptContext - What the scripts of a transaction get to look at
+// besides the stack
+type ScriptContext struct {
+	SigHash  []byte
+	LockTime uint64
+}
+
+/************************************
+ * Script building helpers
+************************************/
+
+// ScriptPushData - Returns the opcodes that push data onto the stack
+func ScriptPushData(data []byte) []byte {
+	n := len(data)
+	switch {
+	case n < OpPushData1:
+		return append([]byte{byte(n)}, data...)
+	case n <= 0xff:
+		return append([]byte{OpPushData1, byte(n)}, data...)
+	}
+	length := make([]byte, 2)
+	binary.BigEndian.PutUint16(length, uint16(n))
+	return append(append([]byte{OpPushData2}, length...), data...)
+}
+
+// ScriptNumber - Encodes a number the way scripts read them: big
+// endian, without leading zero bytes
+func ScriptNumber(n uint64) []byte {
+	return new(big.Int).SetUint64(n).Bytes()
+}
+
+// MarshalScriptPubKey - Encodes a P-384 public key for a script
+func MarshalScriptPubKey(pubKey *ecdsa.PublicKey) []byte {
+	return elliptic.Marshal(elliptic.P384(), pubKey.X, pubKey.Y)
+}
+
+// MarshalScriptSignature - Encodes a P-384 signature for a script
+func MarshalScriptSignature(r *big.Int, s *big.Int) []byte {
+	sig := make([]byte, scriptSignatureLen)
+	r.FillBytes(sig[:scriptSignatureLen/2])
+	s.FillBytes(sig[scriptSignatureLen/2:])
+	return sig
+}
+
+// ScriptAccountID - Derives the identifier of the account that the
+// locking script guards. Send coins to it the same way as to a
+// public key, and spend them by carrying the locking script and an
+// unlocking script that satisfies it
+func ScriptAccountID(lockingScript []byte) *ecdsa.PublicKey {
+	return deriveAccountID("script", lockingScript)
+}
+
+/************************************
+ * Script interpreter
+************************************/
+
+// scriptIsTrue - Any non-zero byte makes an element true
+func scriptIsTrue(element []byte) bool {
+	for _, v := range element {
+		if v != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptBool - Converts a bool into a stack element
+func scriptBool(b bool) []byte {
+	if b {
+		return []byte{1}
+	}
+	return []byte{}
+}
+
+// isPushOp - Returns true if the opcode only pushes data
+func isPushOp(op byte) bool {
+	return op <= OpPushData2 || op == OpTrue
+}
+
+// ExecuteScript - Runs a script on top of the stack and returns the
+// resulting stack. opCount is shared between the scripts of a
+// transaction so the limit covers them together
+func ExecuteScript(script []byte, stack [][]byte, ctx ScriptContext, opCount *int) ([][]byte, error) {
+	if len(script) > MaxScriptSize {
+		return nil, errors.New("ExecuteScript: script is too long")
+	}
+
+	// pop - Removes the top n elements of the stack
+	pop := func(n int) ([][]byte, error) {
+		if len(stack) < n {
+			return nil, errors.New("ExecuteScript: stack underflow")
+		}
+		top := append([][]byte{}, stack[len(stack)-n:]...)
+		stack = stack[:len(stack)-n]
+		return top, nil
+	}
+
+	pc := 0
+	for pc < len(script) {
+		op := script[pc]
+		pc++
+
+		// Data pushes
+		if op <= OpPushData2 {
+			n := int(op)
+			if op == OpPushData1 {
+				if pc+1 > len(script) {
+					return nil, errors.New("ExecuteScript: truncated push")
+				}
+				n = int(script[pc])
+				pc++
+			} else if op == OpPushData2 {
+				if pc+2 > len(script) {
+					return nil, errors.New("ExecuteScript: truncated push")
+				}
+				n = int(binary.BigEndian.Uint16(script[pc : pc+2]))
+				pc += 2
+			}
+			if pc+n > len(script) {
+				return nil, errors.New("ExecuteScript: truncated push")
+			}
+			if n > MaxScriptElementSize {
+				return nil, errors.New("ExecuteScript: pushed element is too large")
+			}
+			stack = append(stack, append([]byte{}, script[pc:pc+n]...))
+			pc += n
+		} else {
+			*opCount++
+			if *opCount > MaxScriptOps {
+				return nil, errors.New("ExecuteScript: too many opcodes")
+			}
+
+			switch op {
+			case OpTrue:
+				stack = append(stack, []byte{1})
+			case OpVerify:
+				top, err := pop(1)
+				if err != nil {
+					return nil, err
+				}
+				if !scriptIsTrue(top[0]) {
+					return nil, errors.New("ExecuteScript: VERIFY failed")
+				}
+			case OpDrop:
+				if _, err := pop(1); err != nil {
+					return nil, err
+				}
+			case OpDup:
+				if len(stack) < 1 {
+					return nil, errors.New("ExecuteScript: stack underflow")
+				}
+				stack = append(stack, stack[len(stack)-1])
+			case OpSwap:
+				if len(stack) < 2 {
+					return nil, errors.New("ExecuteScript: stack underflow")
+				}
+				stack[len(stack)-1], stack[len(stack)-2] = stack[len(stack)-2], stack[len(stack)-1]
+			case OpEqual, OpEqualVerify:
+				top, err := pop(2)
+				if err != nil {
+					return nil, err
+				}
+				equal := bytes.Equal(top[0], top[1])
+				if op == OpEqualVerify {
+					if !equal {
+						return nil, errors.New("ExecuteScript: EQUALVERIFY failed")
+					}
+				} else {
+					stack = append(stack, scriptBool(equal))
+				}
+			case OpNot:
+				top, err := pop(1)
+				if err != nil {
+					return nil, err
+				}
+				stack = append(stack, scriptBool(!scriptIsTrue(top[0])))
+			case OpBoolAnd, OpBoolOr:
+				top, err := pop(2)
+				if err != nil {
+					return nil, err
+				}
+				a, b := scriptIsTrue(top[0]), scriptIsTrue(top[1])
+				if op == OpBoolAnd {
+					stack = append(stack, scriptBool(a && b))
+				} else {
+					stack = append(stack, scriptBool(a || b))
+				}
+			case OpSHA256:
+				top, err := pop(1)
+				if err != nil {
+					return nil, err
+				}
+				hash := sha256.Sum256(top[0])
+				stack = append(stack, hash[:])
+			case OpCheckSig:
+				// The public key is on top, the signature below it
+				top, err := pop(2)
+				if err != nil {
+					return nil, err
+				}
+				stack = append(stack, scriptBool(scriptCheckSig(top[0], top[1], ctx.SigHash)))
+			case OpCheckLockTimeVerify:
+				// Leaves the lock time on the stack, like Bitcoin does
+				if len(stack) < 1 {
+					return nil, errors.New("ExecuteScript: stack underflow")
+				}
+				top := stack[len(stack)-1]
+				if len(top) > 8 {
+					return nil, errors.New("ExecuteScript: lock time is too large")
+				}
+				lockTime := new(big.Int).SetBytes(top).Uint64()
+				if (lockTime < LockTimeThreshold) != (ctx.LockTime < LockTimeThreshold) {
+					return nil, errors.New("ExecuteScript: lock time kinds don't match")
+				}
+				if ctx.LockTime < lockTime {
+					return nil, errors.New("ExecuteScript: CHECKLOCKTIMEVERIFY failed")
+				}
+			default:
+				return nil, errors.New("ExecuteScript: unknown opcode")
+			}
+		}
+
+		if len(stack) > MaxStackSize {
+			return nil, errors.New("ExecuteScript: stack is too large")
+		}
+	}
+
+	return stack, nil
+}
+
+// scriptCheckSig - Verifies a script signature over the sig hash
+func scriptCheckSig(sig []byte, pubKeyBytes []byte, sigHash []byte) bool {
+	if len(sig) != scriptSignatureLen {
+		return false
+	}
+	x, y := elliptic.Unmarshal(elliptic.P384(), pubKeyBytes)
+	if x == nil {
+		return false
+	}
+	pubKey := &ecdsa.PublicKey{Curve: elliptic.P384(), X: x, Y: y}
+	r := new(big.Int).SetBytes(sig[:scriptSignatureLen/2])
+	s := new(big.Int).SetBytes(sig[scriptSignatureLen/2:])
+	return ecdsa.Verify(pubKey, sigHash, r, s)
+}
+
+// VerifyScripts - Runs the unlocking script, then the locking script
+// on the stack it left behind. Returns nil if the locking script
+// ends with a true element on top of the stack. The unlocking
+// script may only push data
+func VerifyScripts(unlockingScript []byte, lockingScript []byte, ctx ScriptContext) error {
+	// Walk the unlocking script to make sure it's push-only
+	for pc := 0; pc < len(unlockingScript); {
+		op := unlockingScript[pc]
+		if !isPushOp(op) {
+			return errors.New("VerifyScripts: unlocking script isn't push-only")
+		}
+		pc++
+		switch {
+		case op == OpPushData1 && pc < len(unlockingScript):
+			pc += 1 + int(unlockingScript[pc])
+		case op == OpPushData2 && pc+1 < len(unlockingScript):
+			pc += 2 + int(binary.BigEndian.Uint16(unlockingScript[pc:pc+2]))
+		case op < OpPushData1:
+			pc += int(op)
+		}
+	}
+
+	opCount := 0
+	stack, err := ExecuteScript(unlockingScript, nil, ctx, &opCount)
+	if err != nil {
+		return err
+	}
+	stack, err = ExecuteScript(lockingScript, stack, ctx, &opCount)
+	if err != nil {
+		return err
+	}
+	if len(stack) == 0 || !scriptIsTrue(stack[len(stack)-1]) {
+		return errors.New("VerifyScripts: script evaluated to false")
+	}
+	return nil
+}
+
+// scriptIsValid - Checks that the input of the transaction is the
+// account of its locking script and that the scripts succeed
+func (t *Transaction) scriptIsValid() bool {
+	if !t.isInput(ScriptAccountID(t.LockingScript)) {
+		return false
+	}
+	ctx := ScriptContext{SigHash: t.SigHash(), LockTime: t.LockTime}
+	return VerifyScripts(t.UnlockingScript, t.LockingScript, ctx) == nil
+}
@@ -0,0 +1,136 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"testing"
+)
+
+// script - Concatenates opcodes and pushes into a script
+func script(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// op - A single opcode as a script part
+func op(code byte) []byte {
+	return []byte{code}
+}
+
+func TestScriptOpcodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		script []byte
+		want   bool
+	}{
+		{"true", op(OpTrue), true},
+		{"false", op(OpFalse), false},
+		{"equal", script(ScriptPushData([]byte("a")), ScriptPushData([]byte("a")), op(OpEqual)), true},
+		{"not equal", script(ScriptPushData([]byte("a")), ScriptPushData([]byte("b")), op(OpEqual)), false},
+		{"not", script(op(OpFalse), op(OpNot)), true},
+		{"booland", script(op(OpTrue), op(OpFalse), op(OpBoolAnd)), false},
+		{"boolor", script(op(OpTrue), op(OpFalse), op(OpBoolOr)), true},
+		{"swap", script(op(OpTrue), op(OpFalse), op(OpSwap)), true},
+		{"dup and drop", script(op(OpFalse), op(OpTrue), op(OpDup), op(OpDrop)), true},
+		{"verify", script(op(OpTrue), op(OpVerify), op(OpTrue)), true},
+		{"zero bytes are false", ScriptPushData([]byte{0, 0}), false},
+		{"pushdata1", script(ScriptPushData(bytes.Repeat([]byte{1}, 100)), ScriptPushData(bytes.Repeat([]byte{1}, 100)), op(OpEqual)), true},
+		{"pushdata2", script(ScriptPushData(bytes.Repeat([]byte{1}, 300)), op(OpDrop), op(OpTrue)), true},
+	}
+	for _, test := range tests {
+		err := VerifyScripts(nil, test.script, ScriptContext{})
+		if (err == nil) != test.want {
+			t.Fatalf("%s: err = %v, want success %v", test.name, err, test.want)
+		}
+	}
+
+	failures := map[string][]byte{
+		"underflow":        op(OpDup),
+		"failed verify":    script(op(OpFalse), op(OpVerify), op(OpTrue)),
+		"equalverify":      script(op(OpTrue), op(OpFalse), op(OpEqualVerify), op(OpTrue)),
+		"unknown opcode":   script(op(OpTrue), op(0xff)),
+		"truncated push":   []byte{5, 1, 2},
+		"too many opcodes": bytes.Repeat(op(OpTrue), MaxScriptOps+1),
+		"oversized push":   script(ScriptPushData(make([]byte, MaxScriptElementSize+1)), op(OpTrue)),
+	}
+	for name, s := range failures {
+		if VerifyScripts(nil, s, ScriptContext{}) == nil {
+			t.Fatalf("%s: script succeeded", name)
+		}
+	}
+}
+
+func TestScriptHashLock(t *testing.T) {
+	secret := []byte("secret")
+	hash := sha256.Sum256(secret)
+	locking := script(op(OpSHA256), ScriptPushData(hash[:]), op(OpEqual))
+	if err := VerifyScripts(ScriptPushData(secret), locking, ScriptContext{}); err != nil {
+		t.Fatal(err)
+	}
+	if VerifyScripts(ScriptPushData([]byte("guess")), locking, ScriptContext{}) == nil {
+		t.Fatal("wrong preimage accepted")
+	}
+
+	// Unlocking scripts can only push data
+	if VerifyScripts(script(ScriptPushData(hash[:]), op(OpDrop), ScriptPushData(secret)), locking, ScriptContext{}) == nil {
+		t.Fatal("unlocking script with an opcode accepted")
+	}
+}
+
+func TestScriptLockTime(t *testing.T) {
+	locking := script(ScriptPushData(ScriptNumber(100)), op(OpCheckLockTimeVerify), op(OpDrop), op(OpTrue))
+	if err := VerifyScripts(nil, locking, ScriptContext{LockTime: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if VerifyScripts(nil, locking, ScriptContext{LockTime: 99}) == nil {
+		t.Fatal("spent before the lock time")
+	}
+	if VerifyScripts(nil, locking, ScriptContext{LockTime: LockTimeThreshold + 100}) == nil {
+		t.Fatal("height lock satisfied by a Unix time")
+	}
+}
+
+func TestScriptCheckSig(t *testing.T) {
+	owner := testKey(t)
+	other := testKey(t)
+	locking := script(ScriptPushData(MarshalScriptPubKey(&owner.PublicKey)), op(OpCheckSig))
+	account := ScriptAccountID(locking)
+
+	spend := func(key *ecdsa.PrivateKey) Transaction {
+		tx := Transaction{
+			Type:          TXTransfer,
+			XInput:        account.X,
+			YInput:        account.Y,
+			XOutput:       other.X,
+			YOutput:       other.Y,
+			Amount:        1,
+			LockingScript: locking,
+		}
+		r, s, err := ecdsa.Sign(crand.Reader, key, tx.SigHash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.UnlockingScript = ScriptPushData(MarshalScriptSignature(r, s))
+		return tx
+	}
+
+	tx := spend(owner)
+	if !tx.TransactionSignatureIsValid() {
+		t.Fatal("spend signed by the owner rejected")
+	}
+	if forged := spend(other); forged.TransactionSignatureIsValid() {
+		t.Fatal("spend signed by someone else accepted")
+	}
+	tx.Amount = 2
+	if tx.TransactionSignatureIsValid() {
+		t.Fatal("amount changed after signing accepted")
+	}
+
+	// The input has to be the account of the locking script
+	wrongInput := spend(owner)
+	wrongInput.XInput, wrongInput.YInput = owner.X, owner.Y
+	if wrongInput.TransactionSignatureIsValid() {
+		t.Fatal("script spend from another account accepted")
+	}
+}
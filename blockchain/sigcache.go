@@ -0,0 +1,134 @@
+package blockchain
+
+import (
+	"runtime"
+	"sync"
+)
+
+const (
+	// DefaultSigCacheSize - How many valid signatures the default
+	// signature cache remembers
+	DefaultSigCacheSize = 100000
+)
+
+// SigCache - Remembers transactions whose signatures were found
+// valid, so a transaction checked when it entered the pool doesn't
+// get checked again when its block arrives. The key is the hash of
+// the whole transaction, signatures included, so a cached entry
+// can't vouch for a different signature. Once full, an arbitrary
+// entry makes room for the new one
+type SigCache struct {
+	entries map[string]struct{}
+	max     int
+	mux     sync.RWMutex
+}
+
+// MakeSigCache - SigCache constructor
+func MakeSigCache(max int) *SigCache {
+	return &SigCache{entries: make(map[string]struct{}), max: max}
+}
+
+// sigCache - The signature cache every signature check goes
+// through. nil turns caching off
+var sigCache = MakeSigCache(DefaultSigCacheSize)
+
+// SetSigCache - Sets the signature cache used in this process.
+// Pass nil to turn caching off
+func SetSigCache(c *SigCache) {
+	sigCache = c
+}
+
+// CurrentSigCache - Returns the signature cache in use
+func CurrentSigCache() *SigCache {
+	return sigCache
+}
+
+// Contains - Returns true if the transaction hash is in the cache
+func (c *SigCache) Contains(txHash []byte) bool {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	_, ok := c.entries[string(txHash)]
+	return ok
+}
+
+// Add - Adds a transaction hash to the cache
+func (c *SigCache) Add(txHash []byte) {
+	if c.max <= 0 {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if len(c.entries) >= c.max {
+		for k := range c.entries {
+			delete(c.entries, k)
+			break
+		}
+	}
+	c.entries[string(txHash)] = struct{}{}
+}
+
+// signatureIsValidCached - TransactionSignatureIsValid, but through
+// the signature cache
+func (t *Transaction) signatureIsValidCached() bool {
+	c := sigCache
+	if c == nil {
+		return t.TransactionSignatureIsValid()
+	}
+
+	hash := t.HashTransaction()
+	if c.Contains(hash) {
+		return true
+	}
+	if !t.TransactionSignatureIsValid() {
+		return false
+	}
+	c.Add(hash)
+	return true
+}
+
+// VerifySignatures - Checks the signatures of the transactions on one
+// worker per CPU. Stops handing out work as soon as a signature
+// fails, and returns the index of the failed transaction. Returns
+// -1 if every signature is valid. Valid signatures go into the
+// signature cache, so checking them again afterwards is cheap
+func VerifySignatures(txs []Transaction) int {
+	workers := runtime.NumCPU()
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+
+	jobs := make(chan int)
+	abort := make(chan struct{})
+	var once sync.Once
+	var workersDone sync.WaitGroup
+	failed := -1
+
+	for w := 0; w < workers; w++ {
+		workersDone.Add(1)
+		go func() {
+			defer workersDone.Done()
+			for i := range jobs {
+				if !txs[i].signatureIsValidCached() {
+					i := i
+					once.Do(func() {
+						failed = i
+						close(abort)
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range txs {
+		select {
+		case jobs <- i:
+		case <-abort:
+			break feed
+		}
+	}
+	close(jobs)
+	workersDone.Wait()
+
+	return failed
+}
@@ -0,0 +1,67 @@
+package blockchain
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestSigCache(t *testing.T) {
+	c := MakeSigCache(2)
+	c.Add([]byte("a"))
+	c.Add([]byte("b"))
+	c.Add([]byte("c"))
+	if !c.Contains([]byte("c")) {
+		t.Fatal("newest entry missing")
+	}
+	if c.Contains([]byte("a")) && c.Contains([]byte("b")) {
+		t.Fatal("full cache didn't make room")
+	}
+
+	off := MakeSigCache(0)
+	off.Add([]byte("a"))
+	if off.Contains([]byte("a")) {
+		t.Fatal("cache of size 0 remembers entries")
+	}
+}
+
+func TestVerifySignatures(t *testing.T) {
+	defer SetSigCache(CurrentSigCache())
+	cache := MakeSigCache(DefaultSigCacheSize)
+	SetSigCache(cache)
+
+	treasury := testKey(t)
+	var txs []Transaction
+	for i := 0; i < 50; i++ {
+		txs = append(txs, transfer(t, treasury, &testKey(t).PublicKey, float64(i+1)))
+	}
+	if failed := VerifySignatures(txs); failed != -1 {
+		t.Fatalf("transaction %d failed", failed)
+	}
+	for i := range txs {
+		if !cache.Contains(txs[i].HashTransaction()) {
+			t.Fatalf("valid transaction %d isn't cached", i)
+		}
+	}
+
+	// A cached signature can't vouch for a different one
+	forged := txs[10]
+	forged.SSignature = new(big.Int).Add(forged.SSignature, big.NewInt(1))
+	if forged.signatureIsValidCached() {
+		t.Fatal("forged signature accepted through the cache")
+	}
+	txs[30] = forged
+	if failed := VerifySignatures(txs); failed != 30 {
+		t.Fatalf("VerifySignatures = %d, want 30", failed)
+	}
+	if cache.Contains(forged.HashTransaction()) {
+		t.Fatal("forged transaction cached")
+	}
+
+	SetSigCache(nil)
+	if failed := VerifySignatures(txs[:30]); failed != -1 {
+		t.Fatalf("transaction %d failed without a cache", failed)
+	}
+	if VerifySignatures(nil) != -1 {
+		t.Fatal("no transactions failed")
+	}
+}
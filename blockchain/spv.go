@@ -0,0 +1,276 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+)
+
+const (
+	// MaxHeadersPerMessage - Maximum number of headers a full node
+	// hands out at once
+	MaxHeadersPerMessage = 2000
+)
+
+// BlockHeader - Everything in a block other than its transactions,
+// which the Merkle root commits to. The hash of a block only
+// covers its header, so a light client can check proof of work
+// and linkage from headers alone
+type BlockHeader struct {
+	Version    uint32 `json:"Version"`
+	Index      uint64 `json:"Index"`
+	Hash       []byte `json:"Hash"`
+	PrevHash   []byte `json:"PrevHash"`
+	Timestamp  uint64 `json:"Timestamp"`
+	Difficulty uint32 `json:"Difficulty"`
+	Nonce      []byte `json:"Nonce"`
+	StateRoot  []byte `json:"StateRoot"`
+	MerkleRoot []byte `json:"MerkleRoot"`
+
+	XSigner    *big.Int `json:"XSigner"`
+	YSigner    *big.Int `json:"YSigner"`
+	RSignature *big.Int `json:"RSignature"`
+	SSignature *big.Int `json:"SSignature"`
+}
+
+// HeaderChain - The chain of block headers a light client keeps
+// instead of the blockchain. Headers holds the heaviest chain; the
+// headers of other branches are kept too, in case they end up
+// heavier
+type HeaderChain struct {
+	Headers []BlockHeader `json:"Headers"`
+
+	genesis []byte
+	blocks  Blockchain // Headers as blocks without transactions
+	nodes   map[string]*headerNode
+}
+
+// headerNode - A verified header, on the heaviest chain or on another
+// branch, with the total weight of the chain ending in it
+type headerNode struct {
+	header BlockHeader
+	weight *big.Int
+}
+
+// MakeHeaderChain - HeaderChain constructor. Only the genesis block
+// with the given hash is accepted, so a light client has to get the
+// hash from somewhere it trusts
+func MakeHeaderChain(genesis []byte) *HeaderChain {
+	return &HeaderChain{genesis: genesis, nodes: make(map[string]*headerNode)}
+}
+
+/************************************
+ * Block headers
+************************************/
+
+// Header - Returns the header of the block
+func (b *Block) Header() BlockHeader {
+	return BlockHeader{
+		Version:    b.Version,
+		Index:      b.Index,
+		Hash:       b.Hash,
+		PrevHash:   b.PrevHash,
+		Timestamp:  b.Timestamp,
+		Difficulty: b.Difficulty,
+		Nonce:      b.Nonce,
+		StateRoot:  b.StateRoot,
+		MerkleRoot: b.MerkleRoot,
+		XSigner:    b.XSigner,
+		YSigner:    b.YSigner,
+		RSignature: b.RSignature,
+		SSignature: b.SSignature,
+	}
+}
+
+// HashHeader - Generates the hash of the block the header belongs
+// to. Only blocks hashed with HashFormatV2 can be hashed from their
+// header: HashFormatV1 covers the transactions themselves, so
+// this returns nil for them
+func (h *BlockHeader) HashHeader() []byte {
+	if RuleSetAt(h.Index).HashFormat != HashFormatV2 {
+		return nil
+	}
+	return h.hashHeaderV2()
+}
+
+// HeaderHashIsValid - Returns true if the hash of the header is
+// correct and its proof of work hash meets the difficulty.
+// Always false for HashFormatV1 headers
+func (h *BlockHeader) HeaderHashIsValid() bool {
+	hash := h.HashHeader()
+	if hash == nil || bytes.Compare(hash, h.Hash) != 0 {
+		return false
+	}
+	return powIsValid(h.Hash, h.Difficulty)
+}
+
+// block - Returns the header as a block without transactions, which
+// is all the consensus engine needs to weigh it
+func (h *BlockHeader) block() Block {
+	return Block{
+		Version:    h.Version,
+		Index:      h.Index,
+		Hash:       h.Hash,
+		PrevHash:   h.PrevHash,
+		Timestamp:  h.Timestamp,
+		Difficulty: h.Difficulty,
+		Nonce:      h.Nonce,
+		StateRoot:  h.StateRoot,
+		MerkleRoot: h.MerkleRoot,
+		XSigner:    h.XSigner,
+		YSigner:    h.YSigner,
+		RSignature: h.RSignature,
+		SSignature: h.SSignature,
+	}
+}
+
+/************************************
+ * Header chain
+************************************/
+
+// Headers - Returns up to max headers of the blockchain,
+// starting at height from
+func (bc *Blockchain) Headers(from uint64, max int) []BlockHeader {
+	var headers []BlockHeader
+	for i := from; i < uint64(len(*bc)) && len(headers) < max; i++ {
+		headers = append(headers, (*bc)[i].Header())
+	}
+	return headers
+}
+
+// Height - Returns how many headers the heaviest chain has
+func (hc *HeaderChain) Height() uint64 {
+	return uint64(len(hc.Headers))
+}
+
+// AddHeaders - Verifies headers a peer sent and adds them to the
+// header chain. The first header has to be the pinned genesis block.
+// Every other header needs a known header as its previous block,
+// the index after it, a valid proof of work, the difficulty the
+// consensus engine expects on top of it, and a timestamp after the
+// median time past. Headers can start a new branch anywhere, and
+// whichever branch has the most cumulative weight becomes the
+// heaviest chain. Headers the chain already has are skipped.
+// Light clients can only follow proof of work chains hashed with
+// HashFormatV2, like the main chain, since the other consensus
+// engines and HashFormatV1 need transactions to check a block
+func (hc *HeaderChain) AddHeaders(headers []BlockHeader) error {
+	for i := range headers {
+		h := headers[i]
+		if _, exists := hc.nodes[string(h.Hash)]; exists {
+			continue
+		}
+		if h.HashHeader() == nil {
+			return errors.New("AddHeaders: HashFormatV1 headers can't be checked without their transactions")
+		}
+
+		b := h.block()
+		weight := consensus.BlockWeight(&b)
+		if h.Index == 0 {
+			if bytes.Compare(h.Hash, hc.genesis) != 0 || bytes.Compare(h.HashHeader(), h.Hash) != 0 {
+				return errors.New("AddHeaders: genesis block doesn't match the pinned hash")
+			}
+		} else {
+			parent, exists := hc.nodes[string(h.PrevHash)]
+			if !exists {
+				return errors.New("AddHeaders: previous hash doesn't match any known header")
+			}
+			if h.Index != parent.header.Index+1 {
+				return errors.New("AddHeaders: index doesn't follow the previous header")
+			}
+			if !h.HeaderHashIsValid() {
+				return errors.New("AddHeaders: invalid proof of work")
+			}
+			prefix := hc.chainTo(parent)
+			if h.Difficulty != consensus.CalcDifficulty(&prefix) {
+				return errors.New("AddHeaders: unexpected difficulty")
+			}
+			if h.Timestamp <= prefix.MedianTimePast() {
+				return errors.New("AddHeaders: timestamp isn't after the median time past")
+			}
+			weight.Add(weight, parent.weight)
+		}
+
+		node := &headerNode{header: h, weight: weight}
+		hc.nodes[string(h.Hash)] = node
+		if len(hc.Headers) == 0 || weight.Cmp(hc.nodes[string(hc.Headers[len(hc.Headers)-1].Hash)].weight) > 0 {
+			hc.switchTo(node)
+		}
+	}
+	return nil
+}
+
+// branchTo - Returns the height where the branch ending in the node
+// leaves the heaviest chain, and the headers of the branch from there
+func (hc *HeaderChain) branchTo(node *headerNode) (uint64, []BlockHeader) {
+	var branch []BlockHeader
+	for {
+		h := node.header
+		if h.Index < hc.Height() && bytes.Compare(hc.Headers[h.Index].Hash, h.Hash) == 0 {
+			return h.Index + 1, branch
+		}
+		branch = append([]BlockHeader{h}, branch...)
+		if h.Index == 0 {
+			return 0, branch
+		}
+		node = hc.nodes[string(h.PrevHash)]
+	}
+}
+
+// chainTo - Returns the chain ending in the node as blocks without
+// transactions, for the consensus engine
+func (hc *HeaderChain) chainTo(node *headerNode) Blockchain {
+	fork, branch := hc.branchTo(node)
+	if len(branch) == 0 {
+		return hc.blocks[:fork:fork]
+	}
+	chain := append(Blockchain{}, hc.blocks[:fork]...)
+	for i := range branch {
+		chain = append(chain, branch[i].block())
+	}
+	return chain
+}
+
+// switchTo - Makes the branch ending in the node the heaviest chain
+func (hc *HeaderChain) switchTo(node *headerNode) {
+	fork, branch := hc.branchTo(node)
+	hc.Headers = hc.Headers[:fork:fork]
+	hc.blocks = hc.blocks[:fork:fork]
+	for i := range branch {
+		hc.Headers = append(hc.Headers, branch[i])
+		hc.blocks = append(hc.blocks, branch[i].block())
+	}
+}
+
+// VerifyInclusion - Checks a Merkle proof from a full node against the
+// header chain. Returns how many confirmations the transaction has
+func (hc *HeaderChain) VerifyInclusion(proof *MerkleProof) (uint64, error) {
+	if proof.Height >= hc.Height() {
+		return 0, errors.New("VerifyInclusion: header chain doesn't reach the block yet")
+	}
+	h := &hc.Headers[proof.Height]
+	if bytes.Compare(h.Hash, proof.BlockHash) != 0 {
+		return 0, errors.New("VerifyInclusion: block isn't on the header chain")
+	}
+	root := proof.MerkleRoot()
+	if root == nil || bytes.Compare(root, h.MerkleRoot) != 0 {
+		return 0, errors.New("VerifyInclusion: proof doesn't lead to the Merkle root")
+	}
+	return hc.Height() - proof.Height, nil
+}
+
+// CheckBlock - Checks that a block fetched from a full node is on the
+// header chain and that its transactions are the ones its Merkle
+// root commits to
+func (hc *HeaderChain) CheckBlock(b *Block) error {
+	if b.Index >= hc.Height() {
+		return errors.New("CheckBlock: header chain doesn't reach the block yet")
+	}
+	if bytes.Compare(hc.Headers[b.Index].Hash, b.Hash) != 0 || bytes.Compare(b.HashBlock(), b.Hash) != 0 {
+		return errors.New("CheckBlock: block isn't on the header chain")
+	}
+	if bytes.Compare(CalcMerkleRoot(b.TXs), b.MerkleRoot) != 0 {
+		return errors.New("CheckBlock: transactions don't match the Merkle root")
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package blockchain
+
+import (
+	"testing"
+)
+
+// extend - Returns the chain with n empty blocks on top
+func extend(t *testing.T, bc Blockchain, n int) Blockchain {
+	t.Helper()
+	chain := append(Blockchain{}, bc...)
+	for i := 0; i < n; i++ {
+		chain = append(chain, nextBlock(t, &chain))
+	}
+	return chain
+}
+
+func TestHeaderChainPinsGenesis(t *testing.T) {
+	defer SetChainParams(ActiveChainParams())
+	SetChainParams(DefaultChainParams())
+
+	bc := extend(t, genesisChain(t), 3)
+	other := genesisChain(t)
+
+	hc := MakeHeaderChain(other[0].Hash)
+	if hc.AddHeaders(bc.Headers(0, MaxHeadersPerMessage)) == nil {
+		t.Fatal("chain from another genesis block accepted")
+	}
+	hc = MakeHeaderChain(bc[0].Hash)
+	if hc.AddHeaders(bc.Headers(1, MaxHeadersPerMessage)) == nil {
+		t.Fatal("headers accepted without the genesis block")
+	}
+	if err := hc.AddHeaders(bc.Headers(0, MaxHeadersPerMessage)); err != nil {
+		t.Fatal(err)
+	}
+	if hc.Height() != 4 {
+		t.Fatalf("height = %d, want 4", hc.Height())
+	}
+	if err := hc.AddHeaders(bc.Headers(2, 1)); err != nil || hc.Height() != 4 {
+		t.Fatal("known headers aren't skipped")
+	}
+
+	// Chains that schedule HashFormatV1 can't be followed by headers
+	p := DefaultChainParams()
+	p.RuleSets = []RuleSet{{Name: "original", HashFormat: HashFormatV1}}
+	SetChainParams(p)
+	v1 := genesisChain(t)
+	if MakeHeaderChain(v1[0].Hash).AddHeaders(v1.Headers(0, MaxHeadersPerMessage)) == nil {
+		t.Fatal("HashFormatV1 header accepted")
+	}
+}
+
+func TestHeaderChainDifficulty(t *testing.T) {
+	defer SetChainParams(ActiveChainParams())
+	SetChainParams(DefaultChainParams())
+
+	bc := extend(t, genesisChain(t), 2)
+	hc := MakeHeaderChain(bc[0].Hash)
+	if err := hc.AddHeaders(bc.Headers(0, MaxHeadersPerMessage)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A block mined at a lower difficulty than the engine asks for
+	easy := nextBlock(t, &bc)
+	easy.Difficulty = 0
+	easy.MineBlock()
+	if hc.AddHeaders([]BlockHeader{easy.Header()}) == nil {
+		t.Fatal("header below the expected difficulty accepted")
+	}
+
+	// A header with a hash that doesn't meet its difficulty
+	forged := nextBlock(t, &bc)
+	forged.Timestamp++
+	if hc.AddHeaders([]BlockHeader{forged.Header()}) == nil {
+		t.Fatal("header with an invalid proof of work accepted")
+	}
+}
+
+func TestHeaderChainFollowsHeaviestBranch(t *testing.T) {
+	defer SetChainParams(ActiveChainParams())
+	SetChainParams(DefaultChainParams())
+
+	genesis := genesisChain(t)
+	a := extend(t, genesis, 3)
+	b := extend(t, a[:2], 1)
+	hc := MakeHeaderChain(genesis[0].Hash)
+	if err := hc.AddHeaders(a.Headers(0, MaxHeadersPerMessage)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A lighter branch is kept but doesn't take over
+	if err := hc.AddHeaders(b.Headers(2, MaxHeadersPerMessage)); err != nil {
+		t.Fatal(err)
+	}
+	if hc.Height() != 4 || string(hc.Headers[3].Hash) != string(a[3].Hash) {
+		t.Fatal("lighter branch took over")
+	}
+
+	// Once it's heavier, it does
+	b = extend(t, b, 2)
+	if err := hc.AddHeaders(b.Headers(3, MaxHeadersPerMessage)); err != nil {
+		t.Fatal(err)
+	}
+	if hc.Height() != 5 || string(hc.Headers[2].Hash) != string(b[2].Hash) || string(hc.Headers[4].Hash) != string(b[4].Hash) {
+		t.Fatal("heavier branch didn't take over")
+	}
+}
+
+func TestMerkleProofs(t *testing.T) {
+	defer SetChainParams(ActiveChainParams())
+	SetChainParams(DefaultChainParams())
+
+	treasury := testKey(t)
+	alice := testKey(t)
+	var txs []Transaction
+	for i := 1; i <= 5; i++ {
+		txs = append(txs, transfer(t, treasury, &alice.PublicKey, float64(i)))
+	}
+	bc := genesisChain(t, txs...)
+	bc = extend(t, bc, 2)
+	hc := MakeHeaderChain(bc[0].Hash)
+	if err := hc.AddHeaders(bc.Headers(0, MaxHeadersPerMessage)); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range txs {
+		proof, err := bc.FindMerkleProof(txs[i].HashTransaction())
+		if err != nil {
+			t.Fatal(err)
+		}
+		confirmations, err := hc.VerifyInclusion(proof)
+		if err != nil {
+			t.Fatalf("transaction %d: %v", i, err)
+		}
+		if confirmations != 3 {
+			t.Fatalf("confirmations = %d, want 3", confirmations)
+		}
+	}
+
+	proof, _ := MakeMerkleProof(&bc[0], 0, 2)
+	proof.TXHash = txs[3].HashTransaction()
+	if _, err := hc.VerifyInclusion(proof); err == nil {
+		t.Fatal("proof of the wrong transaction accepted")
+	}
+	proof, _ = MakeMerkleProof(&bc[0], 0, 2)
+	proof.Siblings = proof.Siblings[1:]
+	if _, err := hc.VerifyInclusion(proof); err == nil {
+		t.Fatal("proof with a missing sibling accepted")
+	}
+
+	if err := hc.CheckBlock(&bc[0]); err != nil {
+		t.Fatal(err)
+	}
+	tampered := bc[0]
+	tampered.TXs = tampered.TXs[1:]
+	if hc.CheckBlock(&tampered) == nil {
+		t.Fatal("block with missing transactions accepted")
+	}
+}
@@ -0,0 +1,212 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+)
+
+const (
+	// MaxTokenSymbolLen - Maximum length of a token symbol
+	MaxTokenSymbolLen = 16
+)
+
+// TokenPayload - What goes in the Data of a token transaction.
+// Mintable is only looked at when the token gets created
+type TokenPayload struct {
+	Symbol   string  `json:"Symbol"`
+	Amount   float64 `json:"Amount"`
+	Mintable bool    `json:"Mintable"`
+}
+
+// Token - The state of a token on the blockchain
+type Token struct {
+	Symbol   string             `json:"Symbol"`
+	XIssuer  *big.Int           `json:"XIssuer"`
+	YIssuer  *big.Int           `json:"YIssuer"`
+	Mintable bool               `json:"Mintable"`
+	Supply   float64            `json:"Supply"`
+	Balances map[string]float64 `json:"Balances"`
+}
+
+// TokenLedger - Every token on the blockchain, keyed by symbol
+type TokenLedger map[string]*Token
+
+// EncodeTokenPayload - Serializes a payload for the Data of a
+// token transaction
+func EncodeTokenPayload(p TokenPayload) ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// DecodeTokenPayload - Deserializes the Data of a token transaction
+func DecodeTokenPayload(data []byte) (*TokenPayload, error) {
+	var p TokenPayload
+	err := json.Unmarshal(data, &p)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// applyTokenTransaction - Applies a token transaction to the ledger.
+// Returns false and leaves the ledger alone if the transaction
+// breaks the rules of the token
+func (l TokenLedger) applyTokenTransaction(t *Transaction) bool {
+	p, err := DecodeTokenPayload(t.Data)
+	if err != nil || p.Amount <= 0 || t.Amount != 0 {
+		return false
+	}
+	if len(p.Symbol) == 0 || len(p.Symbol) > MaxTokenSymbolLen {
+		return false
+	}
+
+	token, exists := l[p.Symbol]
+	input := accountKey(t.XInput, t.YInput)
+	output := accountKey(t.XOutput, t.YOutput)
+
+	switch t.Type {
+	case TXTokenCreate:
+		if exists {
+			return false
+		}
+		l[p.Symbol] = &Token{
+			Symbol:   p.Symbol,
+			XIssuer:  t.XInput,
+			YIssuer:  t.YInput,
+			Mintable: p.Mintable,
+			Supply:   p.Amount,
+			Balances: map[string]float64{input: p.Amount},
+		}
+	case TXTokenMint:
+		if !exists || !token.Mintable {
+			return false
+		}
+		if accountKey(token.XIssuer, token.YIssuer) != input {
+			return false
+		}
+		token.Supply += p.Amount
+		token.Balances[output] += p.Amount
+	case TXTokenTransfer:
+		if !exists || token.Balances[input] < p.Amount {
+			return false
+		}
+		token.Balances[input] -= p.Amount
+		token.Balances[output] += p.Amount
+	case TXTokenBurn:
+		if !exists || token.Balances[input] < p.Amount {
+			return false
+		}
+		token.Balances[input] -= p.Amount
+		token.Supply -= p.Amount
+	default:
+		return false
+	}
+
+	return true
+}
+
+// copyToken - Deep copies a token so it can be changed without
+// touching the ledger it came from
+func copyToken(token *Token) *Token {
+	c := *token
+	c.Balances = make(map[string]float64)
+	for k, v := range token.Balances {
+		c.Balances[k] = v
+	}
+	return &c
+}
+
+// tokenTransactionIsValid - Checks a token transaction against the
+// tokens on the blockchain, counting the token transactions in txpool
+// as if they went in before it
+func (t *Transaction) tokenTransactionIsValid(bc *Blockchain, txpool []Transaction) bool {
+	p, err := DecodeTokenPayload(t.Data)
+	if err != nil {
+		return false
+	}
+
+	// Try the transaction on a copy of the token it touches
+	ledger := bc.CalcTokenLedgerOnBC(-1)
+	scratch := make(TokenLedger)
+	if token, exists := ledger[p.Symbol]; exists {
+		scratch[p.Symbol] = copyToken(token)
+	}
+	for i := range txpool {
+		pending, err := DecodeTokenPayload(txpool[i].Data)
+		if err == nil && pending.Symbol == p.Symbol {
+			scratch.applyTokenTransaction(&txpool[i])
+		}
+	}
+	return scratch.applyTokenTransaction(t)
+}
+
+// CalcTokenLedgerOnBC - Replays every token transaction of the
+// blockchain. The index parameter works the same way as in
+// CalcAccountBalanceOnBC
+func (bc *Blockchain) CalcTokenLedgerOnBC(index int64) TokenLedger {
+	ledger := make(TokenLedger)
+	if bc == nil {
+		return ledger
+	}
+	limit := bc.blockLimit(index)
+	for i := 0; i < limit; i++ {
+		for j := range (*bc)[i].TXs {
+			tx := &(*bc)[i].TXs[j]
+			if tx.Type >= TXTokenCreate && tx.Type <= TXTokenBurn {
+				ledger.applyTokenTransaction(tx)
+			}
+		}
+	}
+	return ledger
+}
+
+// CalcTokenBalanceOnBC - This returns the number of tokens of
+// a symbol associated with a public key on the blockchain.
+// The index parameter works the same way as in
+// CalcAccountBalanceOnBC
+func (bc *Blockchain) CalcTokenBalanceOnBC(symbol string, pubKey *ecdsa.PublicKey, index int64) float64 {
+	token, exists := bc.CalcTokenLedgerOnBC(index)[symbol]
+	if !exists {
+		return 0
+	}
+	return token.Balances[accountKey(pubKey.X, pubKey.Y)]
+}
+
+// CalcTokenBalanceOnTXPool - This returns how much the token
+// transactions in the transaction pool change the balance of
+// a symbol of a public key by
+func CalcTokenBalanceOnTXPool(symbol string, pubKey *ecdsa.PublicKey, txpool []Transaction) float64 {
+	var totalBalance float64 = 0
+	for _, tx := range txpool {
+		if tx.Type < TXTokenCreate || tx.Type > TXTokenBurn {
+			continue
+		}
+		p, err := DecodeTokenPayload(tx.Data)
+		if err != nil || p.Symbol != symbol {
+			continue
+		}
+		if tx.isInput(pubKey) && tx.Type != TXTokenMint {
+			if tx.Type == TXTokenCreate {
+				totalBalance += p.Amount
+			} else {
+				totalBalance -= p.Amount
+			}
+		}
+		if tx.isOutput(pubKey) && (tx.Type == TXTokenMint || tx.Type == TXTokenTransfer) {
+			totalBalance += p.Amount
+		}
+	}
+
+	return totalBalance
+}
+
+// CalcTokenSupplyOnBC - Returns the total supply of a token on the
+// blockchain. The index parameter works the same way as in
+// CalcAccountBalanceOnBC
+func (bc *Blockchain) CalcTokenSupplyOnBC(symbol string, index int64) float64 {
+	token, exists := bc.CalcTokenLedgerOnBC(index)[symbol]
+	if !exists {
+		return 0
+	}
+	return token.Supply
+}
@@ -0,0 +1,142 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"testing"
+)
+
+// tokenTX - Returns a signed token transaction from key
+func tokenTX(t *testing.T, txType uint32, key *ecdsa.PrivateKey, to *ecdsa.PublicKey, p TokenPayload) Transaction {
+	t.Helper()
+	data, err := EncodeTokenPayload(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := transfer(t, key, to, 0)
+	tx.Type = txType
+	tx.Data = data
+	signTX(t, &tx, key)
+	return tx
+}
+
+func TestTokenLifecycle(t *testing.T) {
+	issuer := testKey(t)
+	alice := testKey(t)
+	bc := genesisChain(t)
+
+	add := func(tx Transaction) bool {
+		b := nextBlock(t, &bc, tx)
+		return bc.AddBlock(&b)
+	}
+
+	if !add(tokenTX(t, TXTokenCreate, issuer, &issuer.PublicKey, TokenPayload{Symbol: "GOLD", Amount: 100, Mintable: true})) {
+		t.Fatal("create rejected")
+	}
+	if add(tokenTX(t, TXTokenCreate, alice, &alice.PublicKey, TokenPayload{Symbol: "GOLD", Amount: 1})) {
+		t.Fatal("second create of the same symbol accepted")
+	}
+	if !add(tokenTX(t, TXTokenTransfer, issuer, &alice.PublicKey, TokenPayload{Symbol: "GOLD", Amount: 40})) {
+		t.Fatal("transfer rejected")
+	}
+	if add(tokenTX(t, TXTokenTransfer, alice, &issuer.PublicKey, TokenPayload{Symbol: "GOLD", Amount: 41})) {
+		t.Fatal("transfer of more tokens than the balance accepted")
+	}
+	if add(tokenTX(t, TXTokenMint, alice, &alice.PublicKey, TokenPayload{Symbol: "GOLD", Amount: 10})) {
+		t.Fatal("mint by someone other than the issuer accepted")
+	}
+	if !add(tokenTX(t, TXTokenMint, issuer, &alice.PublicKey, TokenPayload{Symbol: "GOLD", Amount: 10})) {
+		t.Fatal("mint rejected")
+	}
+	if !add(tokenTX(t, TXTokenBurn, alice, &alice.PublicKey, TokenPayload{Symbol: "GOLD", Amount: 5})) {
+		t.Fatal("burn rejected")
+	}
+
+	if balance := bc.CalcTokenBalanceOnBC("GOLD", &issuer.PublicKey, -1); balance != 60 {
+		t.Fatalf("issuer balance = %v, want 60", balance)
+	}
+	if balance := bc.CalcTokenBalanceOnBC("GOLD", &alice.PublicKey, -1); balance != 45 {
+		t.Fatalf("alice balance = %v, want 45", balance)
+	}
+	if supply := bc.CalcTokenSupplyOnBC("GOLD", -1); supply != 105 {
+		t.Fatalf("supply = %v, want 105", supply)
+	}
+
+	// Balances at an earlier height only count the blocks up to it
+	if balance := bc.CalcTokenBalanceOnBC("GOLD", &alice.PublicKey, 2); balance != 0 {
+		t.Fatalf("alice balance before the transfer = %v, want 0", balance)
+	}
+}
+
+func TestTokenRules(t *testing.T) {
+	issuer := testKey(t)
+	bc := genesisChain(t)
+	fixed := tokenTX(t, TXTokenCreate, issuer, &issuer.PublicKey, TokenPayload{Symbol: "FIXED", Amount: 10})
+	b := nextBlock(t, &bc, fixed)
+	if !bc.AddBlock(&b) {
+		t.Fatal("create rejected")
+	}
+
+	invalid := map[string]Transaction{
+		"mint of a fixed supply token": tokenTX(t, TXTokenMint, issuer, &issuer.PublicKey, TokenPayload{Symbol: "FIXED", Amount: 1}),
+		"zero amount":                  tokenTX(t, TXTokenCreate, issuer, &issuer.PublicKey, TokenPayload{Symbol: "ZERO"}),
+		"negative amount":              tokenTX(t, TXTokenTransfer, issuer, &issuer.PublicKey, TokenPayload{Symbol: "FIXED", Amount: -1}),
+		"empty symbol":                 tokenTX(t, TXTokenCreate, issuer, &issuer.PublicKey, TokenPayload{Amount: 1}),
+		"long symbol":                  tokenTX(t, TXTokenCreate, issuer, &issuer.PublicKey, TokenPayload{Symbol: "ABCDEFGHIJKLMNOPQ", Amount: 1}),
+		"unknown token":                tokenTX(t, TXTokenBurn, issuer, &issuer.PublicKey, TokenPayload{Symbol: "NONE", Amount: 1}),
+	}
+	for name, tx := range invalid {
+		if tx.TransactionTypeIsValid(&bc, nil) {
+			t.Fatalf("%s accepted", name)
+		}
+	}
+
+	// Token transactions can't move coins
+	tx := tokenTX(t, TXTokenTransfer, issuer, &issuer.PublicKey, TokenPayload{Symbol: "FIXED", Amount: 1})
+	tx.Amount = 1
+	signTX(t, &tx, issuer)
+	if tx.TransactionTypeIsValid(&bc, nil) {
+		t.Fatal("token transaction with coins accepted")
+	}
+}
+
+func TestTokenTransactionsCountPending(t *testing.T) {
+	issuer := testKey(t)
+	alice := testKey(t)
+	bc := genesisChain(t)
+	b := nextBlock(t, &bc, tokenTX(t, TXTokenCreate, issuer, &issuer.PublicKey, TokenPayload{Symbol: "FIXED", Amount: 10}))
+	if !bc.AddBlock(&b) {
+		t.Fatal("create rejected")
+	}
+
+	// Each transfer is covered by the balance on its own, both
+	// together aren't
+	first := tokenTX(t, TXTokenTransfer, issuer, &alice.PublicKey, TokenPayload{Symbol: "FIXED", Amount: 6})
+	second := tokenTX(t, TXTokenTransfer, issuer, &alice.PublicKey, TokenPayload{Symbol: "FIXED", Amount: 7})
+	b = nextBlock(t, &bc, first, second)
+	if bc.AddBlock(&b) {
+		t.Fatal("block overspending a token balance accepted")
+	}
+	pool := MakeTXPool()
+	if err := pool.AddTransaction(&bc, first); err != nil {
+		t.Fatal(err)
+	}
+	if pool.AddTransaction(&bc, second) == nil {
+		t.Fatal("pool overspending a token balance accepted")
+	}
+
+	// Only one of two creates of the same symbol can go in
+	b = nextBlock(t, &bc,
+		tokenTX(t, TXTokenCreate, issuer, &issuer.PublicKey, TokenPayload{Symbol: "GOLD", Amount: 1}),
+		tokenTX(t, TXTokenCreate, alice, &alice.PublicKey, TokenPayload{Symbol: "GOLD", Amount: 2}))
+	if bc.AddBlock(&b) {
+		t.Fatal("block creating a symbol twice accepted")
+	}
+
+	b = nextBlock(t, &bc, first, tokenTX(t, TXTokenTransfer, issuer, &alice.PublicKey, TokenPayload{Symbol: "FIXED", Amount: 4}))
+	if !bc.AddBlock(&b) {
+		t.Fatal("block spending the whole token balance rejected")
+	}
+	if balance := bc.CalcTokenBalanceOnBC("FIXED", &alice.PublicKey, -1); balance != 10 {
+		t.Fatalf("alice balance = %v, want 10", balance)
+	}
+}
@@ -1,44 +1,172 @@
 package blockchain
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/sha256"
+	"crypto/sha512"
+	"math"
 	"math/big"
-	"strconv"
+	"strings"
+)
+
+const (
+	// TXTransfer - A plain transfer of coins from the input to the output
+	TXTransfer = 0
+
+	// TXStake - Locks Amount of the input's coins as stake
+	TXStake = 1
+
+	// TXUnstake - Starts unbonding Amount of the input's stake. The
+	// coins become spendable again after UnbondingDelay blocks
+	TXUnstake = 2
+
+	// TXSlash - Carries SlashingEvidence in Data. Burns the stake
+	// of the proposer that signed two blocks at the same height
+	TXSlash = 3
+
+	// TXDeploy - Deploys the contract whose code is in Data. The
+	// output has to be ContractAccountID of the transaction
+	TXDeploy = 4
+
+	// TXCall - Calls the contract at the output with Data as input
+	TXCall = 5
+
+	// TXTokenCreate - Creates the token described by the
+	// TokenPayload in Data and gives its supply to the input
+	TXTokenCreate = 6
+
+	// TXTokenMint - Mints more of a mintable token to the output.
+	// Only the creator of the token can mint
+	TXTokenMint = 7
+
+	// TXTokenTransfer - Sends tokens from the input to the output
+	TXTokenTransfer = 8
+
+	// TXTokenBurn - Destroys tokens of the input
+	TXTokenBurn = 9
+
+	// TXHTLCLock - Locks Amount of the input's coins for the output,
+	// who can claim them by revealing the preimage of the hash
+	// lock before the deadline. Data holds an HTLCPayload
+	TXHTLCLock = 10
+
+	// TXHTLCClaim - The recipient of a lock reveals the preimage
+	// and gets its coins
+	TXHTLCClaim = 11
+
+	// TXHTLCRefund - The sender of a lock takes its coins back once
+	// the deadline has passed
+	TXHTLCRefund = 12
+
+	// TXChannelOpen - Locks Amount of the input's coins in a payment
+	// channel to the output. Data holds a ChannelPayload with the
+	// dispute window
+	TXChannelOpen = 13
+
+	// TXChannelClose - Either side closes the channel with the latest
+	// state it has, which starts the dispute window
+	TXChannelClose = 14
+
+	// TXChannelDispute - Overrides the state of a closing channel
+	// with a newer one during the dispute window
+	TXChannelDispute = 15
+
+	// TXChannelSettle - Pays out a closed channel once its dispute
+	// window is over
+	TXChannelSettle = 16
+
+	// TXChannelCoopClose - Closes and pays out a channel right away
+	// with a final state both sides signed
+	TXChannelCoopClose = 17
+)
+
+const (
+	// LockTimeThreshold - LockTime values below this are block
+	// heights, values at or above it are Unix timestamps
+	LockTimeThreshold = 500000000
 )
 
 // Transaction - This struct contains the necessary fields for each transaction
 // on the network
 type Transaction struct {
 	Version    uint32   `json:"Version"`
+	Type       uint32   `json:"Type"`
 	XInput     *big.Int `json:"XInput"`
 	YInput     *big.Int `json:"YInput"`
 	XOutput    *big.Int `json:"XOutput"`
 	YOutput    *big.Int `json:"YOutput"`
 	Amount     float64  `json:"Amount"`
 	Timestamp  uint64   `json:"Timestamp"`
+	LockTime   uint64   `json:"LockTime"`
 	Data       []byte   `json:"Data"`
+	GasLimit   uint64   `json:"GasLimit"`
+	GasPrice   float64  `json:"GasPrice"`
+	KeyType    uint32   `json:"KeyType"` // Signature scheme of the input key
 	RSignature *big.Int `json:"RSignature"`
 	SSignature *big.Int `json:"SSignature"`
+
+	/*Only used when the input is a multisig account*/
+	Multisig   *MultisigPolicy `json:"Multisig"`
+	Signatures []TXSignature   `json:"Signatures"`
+
+	/*Only used when the input is a script account*/
+	LockingScript   []byte `json:"LockingScript"`
+	UnlockingScript []byte `json:"UnlockingScript"`
+
+	/*Only used when a transfer pays an address instead of a
+	public key. XOutput and YOutput stay nil, and the recipient
+	reveals its public key when it spends the coins*/
+	Recipient []byte `json:"Recipient"`
+}
+
+// signingBytes - Everything in the transaction that gets signed,
+// which is everything other than the signatures themselves. The
+// fields are fixed-width or length-prefixed, so no digits can move
+// from one field to the next without changing the signature
+func (t *Transaction) signingBytes() []byte {
+	var buff hashBuffer
+	buff.putUint(uint64(t.Version))
+	buff.putUint(uint64(t.Type))
+	buff.putBig(t.XInput)
+	buff.putBig(t.YInput)
+	buff.putBig(t.XOutput)
+	buff.putBig(t.YOutput)
+	buff.putFloat(t.Amount)
+	buff.putUint(t.Timestamp)
+	buff.putUint(t.LockTime)
+	buff.putBytes(t.Data)
+	buff.putUint(t.GasLimit)
+	buff.putFloat(t.GasPrice)
+	buff.putUint(uint64(t.KeyType))
+	if t.Multisig != nil {
+		buff.putBytes(t.Multisig.convToBytes())
+	} else {
+		buff.putBytes(nil)
+	}
+	buff.putBytes(t.LockingScript)
+	buff.putBytes(t.Recipient)
+
+	return buff
 }
 
 // ConvToBytes - DO NOT use this for serialization. This
 // should only used when shoving a transaction into a hash function
 func (t *Transaction) convToBytes() []byte {
-	var buff string
-	buff += strconv.FormatUint(uint64(t.Version), Base)
-	buff += t.XInput.String()
-	buff += t.YInput.String()
-	buff += t.XOutput.String()
-	buff += t.YOutput.String()
-	buff += strconv.FormatFloat(float64(t.Amount), 'f', -1, 64)
-	buff += strconv.FormatUint(t.Timestamp, Base)
-	buff += string(t.Data)
-	buff += t.RSignature.String()
-	buff += t.SSignature.String()
-
-	return []byte(buff)
+	buff := hashBuffer(t.signingBytes())
+	buff.putBig(t.RSignature)
+	buff.putBig(t.SSignature)
+	buff.putUint(uint64(len(t.Signatures)))
+	for _, sig := range t.Signatures {
+		buff.putBig(sig.XSigner)
+		buff.putBig(sig.YSigner)
+		buff.putBig(sig.RSignature)
+		buff.putBig(sig.SSignature)
+	}
+	buff.putBytes(t.UnlockingScript)
+
+	return buff
 }
 
 // HashTransaction - Returns a SHA 256 hash for the transaction
@@ -48,11 +176,180 @@ func (t *Transaction) HashTransaction() []byte {
 	return hash[:]
 }
 
+// SigHash - Returns the SHA 256 hash that gets signed. Unlike
+// HashTransaction, it doesn't cover the signatures, so it stays
+// the same once they are filled in
+func (t *Transaction) SigHash() []byte {
+	hash := sha256.Sum256(t.signingBytes())
+	return hash[:]
+}
+
+// deriveAccountID - Derives the identifier of an account that isn't
+// a single public key (multisig, script...) from the data that
+// defines it. The tag keeps the different kinds of accounts apart
+func deriveAccountID(tag string, data []byte) *ecdsa.PublicKey {
+	hash := sha512.Sum512(append([]byte(tag), data...))
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P384(),
+		X:     new(big.Int).SetBytes(hash[:32]),
+		Y:     new(big.Int).SetBytes(hash[32:]),
+	}
+}
+
+// isInput - Returns true if the public key pays for the transaction
+func (t *Transaction) isInput(pubKey *ecdsa.PublicKey) bool {
+	return strings.Compare(pubKey.X.String(), t.XInput.String()) == 0 &&
+		strings.Compare(pubKey.Y.String(), t.YInput.String()) == 0
+}
+
+// isOutput - Returns true if the public key receives the transaction
+func (t *Transaction) isOutput(pubKey *ecdsa.PublicKey) bool {
+	if t.Recipient != nil {
+		return bytes.Compare(t.Recipient, AddressHash(pubKey)) == 0
+	}
+	return strings.Compare(pubKey.X.String(), t.XOutput.String()) == 0 &&
+		strings.Compare(pubKey.Y.String(), t.YOutput.String()) == 0
+}
+
+// balanceDelta - Returns how much the transaction changes the
+// spendable balance of the public key by
+func (t *Transaction) balanceDelta(pubKey *ecdsa.PublicKey) float64 {
+	var delta float64 = 0
+
+	// Claims and refunds pay out coins that were already
+	// taken from the sender of the lock
+	if t.Type == TXHTLCClaim || t.Type == TXHTLCRefund {
+		if t.isInput(pubKey) {
+			delta += t.Amount
+		}
+		return delta
+	}
+
+	// Unstaked coins come back through the unbonding queue,
+	// slashing only touches stake, and channels pay out through
+	// the channel ledger, so none of these move spendable
+	// coins directly
+	if t.Type == TXUnstake || t.Type == TXSlash ||
+		(t.Type > TXChannelOpen && t.Type <= TXChannelCoopClose) {
+		return 0
+	}
+
+	if t.isInput(pubKey) {
+		delta -= t.Amount + t.Fee()
+	}
+	// Staked, HTLC-locked and channel-locked coins leave
+	// the balance without going anywhere
+	if t.isOutput(pubKey) && t.Type != TXStake && t.Type != TXHTLCLock && t.Type != TXChannelOpen {
+		delta += t.Amount
+	}
+
+	return delta
+}
+
+// Fee - Returns the gas fee the input pays for a contract
+// transaction. The whole gas limit is paid for, whether or
+// not execution uses it up
+func (t *Transaction) Fee() float64 {
+	if t.Type != TXDeploy && t.Type != TXCall {
+		return 0
+	}
+	return float64(t.GasLimit) * t.GasPrice
+}
+
+// amountsAreValid - Checks that the amount and gas price are
+// finite and not negative, since a negative one would credit the
+// input instead of debiting it, and that neither the fee nor the
+// total the input pays overflows
+func (t *Transaction) amountsAreValid() bool {
+	isAmount := func(f float64) bool { return f >= 0 && !math.IsInf(f, 1) }
+	if !isAmount(t.Amount) || !isAmount(t.GasPrice) {
+		return false
+	}
+	if fee := t.Fee(); !isAmount(fee) || !isAmount(t.Amount+fee) {
+		return false
+	}
+
+	// CALLVALUE has to fit in a word of the VM context
+	return t.Type != TXCall || t.Amount*ValueUnits < math.MaxUint64
+}
+
+// TransactionTypeIsValid - Checks the rules specific to the
+// type of the transaction, and the limits of the rule set of
+// the next block on top of the blockchain. The transactions in
+// txpool count as if they went in before it, the same way as in
+// TransactionCostIsValid
+func (t *Transaction) TransactionTypeIsValid(bc *Blockchain, txpool []Transaction) bool {
+	// Check the limits of the rule set of the next block
+	rules := bc.ActiveRuleSet(uint64(len(*bc)))
+	if !rules.TransactionSizeIsValid(t) {
+		return false
+	}
+	if !t.amountsAreValid() {
+		return false
+	}
+
+	// Only plain transfers can pay an address
+	if t.Recipient != nil {
+		if t.Type != TXTransfer || len(t.Recipient) != AddressHashLen || t.XOutput != nil || t.YOutput != nil {
+			return false
+		}
+	}
+
+	switch t.Type {
+	case TXTransfer:
+		return true
+	case TXStake:
+		return t.Amount > 0
+	case TXUnstake:
+		return t.unstakeIsValid(bc, txpool)
+	case TXSlash:
+		return t.slashIsValid(bc, txpool)
+	case TXDeploy:
+		if len(t.Data) == 0 || t.GasLimit == 0 {
+			return false
+		}
+		return t.isOutput(ContractAccountID(t))
+	case TXCall:
+		return t.GasLimit > 0
+	case TXTokenCreate, TXTokenMint, TXTokenTransfer, TXTokenBurn:
+		return t.tokenTransactionIsValid(bc, txpool)
+	case TXHTLCLock, TXHTLCClaim, TXHTLCRefund:
+		return t.htlcTransactionIsValid(bc)
+	case TXChannelOpen, TXChannelClose, TXChannelDispute, TXChannelSettle, TXChannelCoopClose:
+		return t.channelTransactionIsValid(bc, txpool)
+	}
+
+	return false
+}
+
+// TransactionIsFinal - Checks to see if the transaction can be
+// included in a block at the given height with the given timestamp.
+// A LockTime of zero means the transaction is never locked. A
+// LockTime below LockTimeThreshold is the first height the
+// transaction can go in, otherwise it's the first Unix time
+func (t *Transaction) TransactionIsFinal(height uint64, blockTime uint64) bool {
+	if t.LockTime == 0 {
+		return true
+	}
+	if t.LockTime < LockTimeThreshold {
+		return height >= t.LockTime
+	}
+	return blockTime >= t.LockTime
+}
+
 // TransactionSignatureIsValid - Checks to see if the
-// signature of the transaction is valid
+// signature of the transaction is valid, using the signature
+// scheme of its KeyType. If the input is a multisig account,
+// checks that enough of its keys signed. If the input is a
+// script account, runs its scripts instead
 func (t *Transaction) TransactionSignatureIsValid() bool {
-	pubKey := &ecdsa.PublicKey{elliptic.P384(), t.XInput, t.YInput}
-	return ecdsa.Verify(pubKey, t.HashTransaction(), t.RSignature, t.SSignature)
+	if t.Multisig != nil {
+		return t.multisigIsValid()
+	}
+	if t.LockingScript != nil {
+		return t.scriptIsValid()
+	}
+	return verifySignature(t.KeyType, t.XInput, t.YInput, t.SigHash(), t.RSignature, t.SSignature)
 }
 
 // TransactionCostIsValid - Checks to see if the person
@@ -64,7 +361,14 @@ func (t *Transaction) TransactionSignatureIsValid() bool {
 // you would like to go up until. If that number is -1, that means
 // you have to go up the entire blockchain and check everything
 func (t *Transaction) TransactionCostIsValid(bc *Blockchain, txpool []Transaction, index int64) bool {
-	pubKey := &ecdsa.PublicKey{elliptic.P384(), t.XInput, t.YInput}
+	// Unstakes, claims, refunds and channel updates pay out
+	// locked coins instead of spending any
+	if t.Type == TXUnstake || t.Type == TXHTLCClaim || t.Type == TXHTLCRefund ||
+		(t.Type > TXChannelOpen && t.Type <= TXChannelCoopClose) {
+		return true
+	}
+
+	pubKey := &ecdsa.PublicKey{Curve: elliptic.P384(), X: t.XInput, Y: t.YInput}
 	var curAccountBalance float64
 
 	// Get the current
@@ -72,7 +376,7 @@ func (t *Transaction) TransactionCostIsValid(bc *Blockchain, txpool []Transactio
 	curAccountBalance += CalcAccountBalanceOnTXPool(pubKey, txpool)
 
 	// Check to see if we have enough money to pay
-	if t.Amount-curAccountBalance >= 0 {
+	if curAccountBalance-(t.Amount+t.Fee()) >= 0 {
 		return true
 	}
 
@@ -0,0 +1,89 @@
+package blockchain
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestSignatureCoversFieldBoundaries(t *testing.T) {
+	key := testKey(t)
+	other := testKey(t)
+
+	// Under the original encoding, "12" + "3" and "1" + "23"
+	// signed the same bytes
+	tx := transfer(t, key, &other.PublicKey, 1)
+	tx.Timestamp = 12
+	tx.LockTime = 3
+	signTX(t, &tx, key)
+	if !tx.TransactionSignatureIsValid() {
+		t.Fatal("signed transaction rejected")
+	}
+
+	shifted := tx
+	shifted.Timestamp = 1
+	shifted.LockTime = 23
+	if bytes.Compare(shifted.SigHash(), tx.SigHash()) == 0 {
+		t.Fatal("moving digits from Timestamp to LockTime keeps the signature hash")
+	}
+	if shifted.TransactionSignatureIsValid() {
+		t.Fatal("transaction with shifted fields accepted")
+	}
+
+	data := tx
+	data.Data = []byte("1")
+	data.GasLimit = 0
+	moved := tx
+	moved.Data = nil
+	moved.GasLimit = 10
+	if bytes.Compare(data.SigHash(), moved.SigHash()) == 0 {
+		t.Fatal("moving bytes from Data to GasLimit keeps the signature hash")
+	}
+}
+
+func TestNegativeAmountsAndGasPrices(t *testing.T) {
+	treasury := testKey(t)
+	alice := testKey(t)
+	bob := testKey(t)
+	bc := genesisChain(t, transfer(t, treasury, &alice.PublicKey, 10))
+
+	// A call with a negative gas price has a negative fee, which
+	// used to credit the input
+	call := func(amount float64, gasLimit uint64, gasPrice float64) Transaction {
+		tx := transfer(t, alice, &bob.PublicKey, amount)
+		tx.Type = TXCall
+		tx.GasLimit = gasLimit
+		tx.GasPrice = gasPrice
+		signTX(t, &tx, alice)
+		return tx
+	}
+	exploits := map[string]Transaction{
+		"negative gas price": call(0, 1000, -1),
+		"negative amount":    transfer(t, alice, &bob.PublicKey, -100),
+		"NaN amount":         transfer(t, alice, &bob.PublicKey, math.NaN()),
+		"overflowing fee":    call(0, math.MaxUint64, math.MaxFloat64),
+		"huge call value":    call(math.MaxFloat64, 1, 0),
+	}
+	for name, tx := range exploits {
+		if MakeTXPool().AddTransaction(&bc, tx) == nil {
+			t.Fatalf("%s: accepted into the pool", name)
+		}
+		b := nextBlock(t, &bc, tx)
+		if bc.BlockIsValid(&b, nil) {
+			t.Fatalf("%s: block accepted", name)
+		}
+	}
+	if balance := bc.CalcAccountBalanceOnBC(&alice.PublicKey, -1); balance != 10 {
+		t.Fatalf("balance = %v, want 10", balance)
+	}
+
+	// Paying for gas still works
+	paid := call(1, 1000, 0.001)
+	b := nextBlock(t, &bc, paid)
+	if !bc.AddBlock(&b) {
+		t.Fatal("funded call rejected")
+	}
+	if balance := bc.CalcAccountBalanceOnBC(&alice.PublicKey, -1); balance != 8 {
+		t.Fatalf("balance after the call = %v, want 8", balance)
+	}
+}
@@ -0,0 +1,202 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// TXLocation - Where a transaction sits on the blockchain
+type TXLocation struct {
+	BlockHash []byte `json:"BlockHash"`
+	Height    uint64 `json:"Height"`
+	Index     int    `json:"Index"`
+}
+
+// TXIndex - Maps the hash of every transaction on the blockchain to
+// where it sits. Register it with RegisterIndexer to keep it up to
+// date. If it has a path, every connected or disconnected block gets
+// appended to the file there as one batch of records, and
+// MakeTXIndex replays them
+type TXIndex struct {
+	path    string
+	entries map[string]TXLocation
+	mux     sync.RWMutex
+}
+
+// txIndexRecord - One line of the index file. A record without
+// a Location removes the entry
+type txIndexRecord struct {
+	Hash     string      `json:"Hash"`
+	Location *TXLocation `json:"Location"`
+}
+
+// MakeTXIndex - TXIndex constructor. Loads the index from the file at
+// path if there is one, and compacts the file down to one record per
+// entry. A record cut off by a crash at the end of the file gets
+// dropped. Pass an empty path to keep it in memory only
+func MakeTXIndex(path string) (*TXIndex, error) {
+	ix := &TXIndex{path: path, entries: make(map[string]TXLocation)}
+	if path == "" {
+		return ix, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		var r txIndexRecord
+		err = json.Unmarshal(line, &r)
+		if err != nil {
+			if i == len(lines)-1 {
+				break
+			}
+			return nil, err
+		}
+		ix.apply([]txIndexRecord{r})
+	}
+
+	err = ix.compact()
+	if err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// apply - Applies records to the entries. Needs the lock held
+func (ix *TXIndex) apply(records []txIndexRecord) {
+	for _, r := range records {
+		if r.Location == nil {
+			delete(ix.entries, r.Hash)
+		} else {
+			ix.entries[r.Hash] = *r.Location
+		}
+	}
+}
+
+// encodeRecords - Returns the lines of the index file holding
+// the records
+func encodeRecords(records []txIndexRecord) ([]byte, error) {
+	var buff []byte
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return nil, err
+		}
+		buff = append(buff, line...)
+		buff = append(buff, '\n')
+	}
+	return buff, nil
+}
+
+// write - Applies records to the entries and appends them to the
+// index file. Needs the lock held
+func (ix *TXIndex) write(records []txIndexRecord) error {
+	ix.apply(records)
+	if ix.path == "" || len(records) == 0 {
+		return nil
+	}
+	data, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(ix.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// compact - Rewrites the index file with one record per entry.
+// Writes to a temporary file first so a crash can't leave half an
+// index behind. Needs the lock held
+func (ix *TXIndex) compact() error {
+	var records []txIndexRecord
+	for hash, loc := range ix.entries {
+		loc := loc
+		records = append(records, txIndexRecord{Hash: hash, Location: &loc})
+	}
+	data, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(ix.path+".tmp", data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(ix.path+".tmp", ix.path)
+}
+
+// ConnectBlock - Adds every transaction of the block to the index
+func (ix *TXIndex) ConnectBlock(b *Block, height uint64) error {
+	ix.mux.Lock()
+	defer ix.mux.Unlock()
+	var records []txIndexRecord
+	for i := range b.TXs {
+		records = append(records, txIndexRecord{
+			Hash:     hex.EncodeToString(b.TXs[i].HashTransaction()),
+			Location: &TXLocation{BlockHash: b.Hash, Height: height, Index: i},
+		})
+	}
+	return ix.write(records)
+}
+
+// DisconnectBlock - Removes every transaction of the block from
+// the index, unless the index already points somewhere else
+func (ix *TXIndex) DisconnectBlock(b *Block, height uint64) error {
+	ix.mux.Lock()
+	defer ix.mux.Unlock()
+	var records []txIndexRecord
+	for i := range b.TXs {
+		key := hex.EncodeToString(b.TXs[i].HashTransaction())
+		if loc, ok := ix.entries[key]; ok && bytes.Compare(loc.BlockHash, b.Hash) == 0 {
+			records = append(records, txIndexRecord{Hash: key})
+		}
+	}
+	return ix.write(records)
+}
+
+// Location - Returns where the transaction with the given hash sits
+func (ix *TXIndex) Location(txHash []byte) (TXLocation, bool) {
+	ix.mux.RLock()
+	defer ix.mux.RUnlock()
+	loc, ok := ix.entries[hex.EncodeToString(txHash)]
+	return loc, ok
+}
+
+// LookupTransaction - Returns the transaction with the given hash and
+// its number of confirmations: 1 if it's in the last block of the
+// blockchain, 2 if it's in the one before, and so on
+func (ix *TXIndex) LookupTransaction(bc *Blockchain, txHash []byte) (*Transaction, uint64, error) {
+	loc, ok := ix.Location(txHash)
+	if !ok {
+		return nil, 0, errors.New("LookupTransaction: transaction not found")
+	}
+	if loc.Height >= uint64(len(*bc)) || bytes.Compare((*bc)[loc.Height].Hash, loc.BlockHash) != 0 {
+		return nil, 0, errors.New("LookupTransaction: index is out of date with the blockchain")
+	}
+	b := &(*bc)[loc.Height]
+	if loc.Index >= len(b.TXs) {
+		return nil, 0, errors.New("LookupTransaction: index is out of date with the blockchain")
+	}
+
+	tx := b.TXs[loc.Index]
+	return &tx, uint64(len(*bc)) - loc.Height, nil
+}
@@ -0,0 +1,117 @@
+package blockchain
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTXIndexPersists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "txindex")
+
+	ix, err := MakeTXIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterIndexer(ix)
+	defer UnregisterIndexer(ix)
+
+	treasury := testKey(t)
+	alice := testKey(t)
+	first := transfer(t, treasury, &alice.PublicKey, 10)
+	bc := genesisChain(t, first)
+	connectBlock(&bc[0], 0)
+	second := transfer(t, alice, &treasury.PublicKey, 1)
+	b := nextBlock(t, &bc, second)
+	if !bc.AddBlock(&b) {
+		t.Fatal("block rejected")
+	}
+
+	tx, confirmations, err := ix.LookupTransaction(&bc, second.HashTransaction())
+	if err != nil || confirmations != 1 || tx.Amount != 1 {
+		t.Fatalf("lookup = %v, %d, %v", tx, confirmations, err)
+	}
+
+	// Every block gets appended to the file, not rewritten
+	before, _ := ioutil.ReadFile(path)
+	disconnectBlock(&bc[1], 1)
+	after, _ := ioutil.ReadFile(path)
+	if len(after) <= len(before) || string(after[:len(before)]) != string(before) {
+		t.Fatal("disconnecting a block didn't append to the index file")
+	}
+
+	// A record cut off at the end of the file gets dropped, and
+	// everything before it survives
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(`{"Hash":"ab`))
+	f.Close()
+
+	reopened, err := MakeTXIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reopened.Location(first.HashTransaction()); !ok {
+		t.Fatal("connected transaction lost on reload")
+	}
+	if _, ok := reopened.Location(second.HashTransaction()); ok {
+		t.Fatal("disconnected transaction back after reload")
+	}
+}
+
+func TestTXIndexReportsWriteErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ix, err := MakeTXIndex(filepath.Join(dir, "missing", "txindex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := Block{TXs: []Transaction{{Amount: 1}}}
+	if ix.ConnectBlock(&b, 0) == nil {
+		t.Fatal("write to a missing directory didn't fail")
+	}
+}
+
+func TestTransactionsOnlyGoInOnce(t *testing.T) {
+	treasury := testKey(t)
+	alice := testKey(t)
+	bc := genesisChain(t, transfer(t, treasury, &alice.PublicKey, 10))
+	tx := transfer(t, alice, &treasury.PublicKey, 1)
+
+	// The index is keyed by the hash, so a second copy would
+	// overwrite where the first one is
+	b := nextBlock(t, &bc, tx, tx)
+	if bc.AddBlock(&b) {
+		t.Fatal("block with the same transaction twice accepted")
+	}
+	b = nextBlock(t, &bc, tx)
+	if !bc.AddBlock(&b) {
+		t.Fatal("block rejected")
+	}
+	b = nextBlock(t, &bc, tx)
+	if bc.AddBlock(&b) {
+		t.Fatal("block replaying a transaction accepted")
+	}
+	if err := MakeTXPool().AddTransaction(&bc, tx); err == nil {
+		t.Fatal("pool took a transaction that's on the blockchain")
+	}
+
+	// The same payment signed again is a different transaction
+	again := transfer(t, alice, &treasury.PublicKey, 1)
+	b = nextBlock(t, &bc, again)
+	if !bc.AddBlock(&b) {
+		t.Fatal("second payment rejected")
+	}
+}
@@ -0,0 +1,114 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"time"
+)
+
+// TXPool - The pending transactions that haven't made
+// it into a block yet
+type TXPool struct {
+	TXs []Transaction `json:"TXs"`
+	mux sync.Mutex
+}
+
+// MakeTXPool - TXPool constructor
+func MakeTXPool() *TXPool {
+	return &TXPool{TXs: make([]Transaction, 0)}
+}
+
+// AddTransaction - Adds a transaction to the pool if it could go
+// into the next block of the blockchain right now. Transactions
+// that are still time-locked get rejected and have to be
+// resubmitted once their lock time has passed. A transaction
+// already in the pool or on the blockchain, or a second claim or
+// refund of the same HTLC, gets rejected. The rules of its type get
+// checked with the pool counted in, and the input has to be able to
+// pay for it on top of everything it spends in the pool already. A valid
+// signature goes into the signature cache, so the block carrying
+// the transaction doesn't have to check it again
+func (p *TXPool) AddTransaction(bc *Blockchain, t Transaction) error {
+	if !t.TransactionIsFinal(uint64(len(*bc)), uint64(time.Now().Unix())) {
+		return errors.New("AddTransaction: transaction is still time-locked")
+	}
+	if !t.signatureIsValidCached() {
+		return errors.New("AddTransaction: invalid transaction signature")
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	hash := t.HashTransaction()
+	for i := range p.TXs {
+		if bytes.Compare(p.TXs[i].HashTransaction(), hash) == 0 {
+			return errors.New("AddTransaction: transaction is already in the pool")
+		}
+	}
+	if bc.transactionHashesOnBC()[string(hash)] {
+		return errors.New("AddTransaction: transaction is already on the blockchain")
+	}
+	if !t.TransactionTypeIsValid(bc, p.TXs) {
+		return errors.New("AddTransaction: transaction breaks the rules of its type")
+	}
+	if t.htlcSettledIn(p.TXs) {
+		return errors.New("AddTransaction: the pool already settles the HTLC")
+	}
+	if !t.TransactionCostIsValid(bc, p.TXs, -1) {
+		return errors.New("AddTransaction: input can't pay for the transaction")
+	}
+	p.TXs = append(p.TXs, t)
+	publishTX(EventTXAccepted, &t)
+	return nil
+}
+
+// Pending - Returns a copy of the transactions in the pool
+func (p *TXPool) Pending() []Transaction {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	txs := make([]Transaction, len(p.TXs))
+	copy(txs, p.TXs)
+	return txs
+}
+
+// RemoveBlockTransactions - Removes every transaction of
+// the block from the pool
+func (p *TXPool) RemoveBlockTransactions(b *Block) {
+	included := make(map[string]bool)
+	for _, tx := range b.TXs {
+		included[string(tx.HashTransaction())] = true
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	var remaining []Transaction
+	for _, tx := range p.TXs {
+		if !included[string(tx.HashTransaction())] {
+			remaining = append(remaining, tx)
+		}
+	}
+	p.TXs = remaining
+}
+
+// EvictInvalid - Removes every transaction that can no longer go into
+// the next block of the blockchain, for example because a block
+// spent the coins it was going to spend. Call this after the chain
+// changes. Returns the evicted transactions
+func (p *TXPool) EvictInvalid(bc *Blockchain) []Transaction {
+	p.mux.Lock()
+	var remaining []Transaction
+	var evicted []Transaction
+	for _, tx := range p.TXs {
+		if tx.TransactionTypeIsValid(bc, remaining) && tx.TransactionCostIsValid(bc, remaining, -1) && !tx.htlcSettledIn(remaining) {
+			remaining = append(remaining, tx)
+		} else {
+			evicted = append(evicted, tx)
+		}
+	}
+	p.TXs = remaining
+	p.mux.Unlock()
+
+	for i := range evicted {
+		publishTX(EventTXEvicted, &evicted[i])
+	}
+	return evicted
+}
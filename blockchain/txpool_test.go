@@ -0,0 +1,43 @@
+package blockchain
+
+import "testing"
+
+func TestTXPoolDuplicatesAndPendingSpends(t *testing.T) {
+	treasury := testKey(t)
+	alice := testKey(t)
+	bob := testKey(t)
+	bc := genesisChain(t, transfer(t, treasury, &alice.PublicKey, 10))
+	pool := MakeTXPool()
+
+	first := transfer(t, alice, &bob.PublicKey, 6)
+	if err := pool.AddTransaction(&bc, first); err != nil {
+		t.Fatal(err)
+	}
+	if pool.AddTransaction(&bc, first) == nil {
+		t.Fatal("the same transaction went into the pool twice")
+	}
+
+	// Alice only has 4 left once the first spend goes through
+	if pool.AddTransaction(&bc, transfer(t, alice, &bob.PublicKey, 5)) == nil {
+		t.Fatal("spend of coins already spent in the pool accepted")
+	}
+	if err := pool.AddTransaction(&bc, transfer(t, alice, &bob.PublicKey, 4)); err != nil {
+		t.Fatal(err)
+	}
+	// Bob can spend what's coming to him in the pool, but no more
+	if pool.AddTransaction(&bc, transfer(t, bob, &alice.PublicKey, 11)) == nil {
+		t.Fatal("overdraft on pending coins accepted")
+	}
+	if len(pool.Pending()) != 2 {
+		t.Fatalf("pool holds %d transactions, want 2", len(pool.Pending()))
+	}
+
+	b := nextBlock(t, &bc, pool.Pending()...)
+	if !bc.AddBlock(&b) {
+		t.Fatal("block of the pool rejected")
+	}
+	pool.RemoveBlockTransactions(&b)
+	if len(pool.Pending()) != 0 {
+		t.Fatal("included transactions left in the pool")
+	}
+}
@@ -0,0 +1,190 @@
+package blockchain
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// MedianTimeSpan - How many blocks the median time past is
+	// taken over
+	MedianTimeSpan = 11
+
+	// MaxFutureBlockTime - How far, in seconds, the timestamp of a
+	// block can be ahead of the clock of the node
+	MaxFutureBlockTime = 2 * 60 * 60
+)
+
+// chainMux - Guards the slice header of a blockchain while blocks get
+// appended or the chain gets replaced, so Snapshot can copy it
+// from another goroutine
+var chainMux sync.RWMutex
+
+// VerifyError - The first block of a chain that failed
+// verification and the reason it failed
+type VerifyError struct {
+	Height uint64 `json:"Height"`
+	Reason string `json:"Reason"`
+}
+
+// Error - Formats the failing height and the reason
+func (e *VerifyError) Error() string {
+	return "VerifyChain: block " + strconv.FormatUint(e.Height, Base) + ": " + e.Reason
+}
+
+// Snapshot - Returns a copy of the blockchain that stays the same
+// while the node keeps adding blocks or reorganizing. The blocks
+// themselves are shared, since blocks never change once added
+func (bc *Blockchain) Snapshot() Blockchain {
+	chainMux.RLock()
+	defer chainMux.RUnlock()
+	snapshot := make(Blockchain, len(*bc))
+	copy(snapshot, *bc)
+	return snapshot
+}
+
+// VerifyChain - Rechecks every block from height from up to (but not
+// including) height to against the blocks below it: the hash, the
+// seal, the link to the previous block, the timestamp, the Merkle
+// and state roots and every transaction signature, balance and type
+// rule. Pass -1 as to for the whole chain. Works on a snapshot, so it's safe to run while
+// the node is live. Returns a *VerifyError for the first block
+// that fails, or nil
+// (@TODO-OPTIMIZE)
+func (bc *Blockchain) VerifyChain(from uint64, to int64) error {
+	snapshot := bc.Snapshot()
+	end := uint64(len(snapshot))
+	if to >= 0 && uint64(to) < end {
+		end = uint64(to)
+	}
+	for height := from; height < end; height++ {
+		if reason := snapshot.verifyBlock(height); reason != "" {
+			return &VerifyError{Height: height, Reason: reason}
+		}
+	}
+	return nil
+}
+
+// MedianTimePast - Returns the median timestamp of the last
+// MedianTimeSpan blocks, or 0 for an empty blockchain. Every block
+// has to be newer than the median time past of the blocks below
+// it, so one miner with a clock that's off can't drag the time of
+// the chain back
+func (bc *Blockchain) MedianTimePast() uint64 {
+	start := len(*bc) - MedianTimeSpan
+	if start < 0 {
+		start = 0
+	}
+	var timestamps []uint64
+	for _, b := range (*bc)[start:] {
+		timestamps = append(timestamps, b.Timestamp)
+	}
+	if len(timestamps) == 0 {
+		return 0
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	return timestamps[len(timestamps)/2]
+}
+
+// verifyBlock - Checks the block at the given height against the
+// blocks below it. Returns why the block is invalid, or an empty
+// string if it's valid
+func (bc Blockchain) verifyBlock(height uint64) string {
+	prefix := bc[:height]
+	return prefix.checkBlock(&bc[height], nil)
+}
+
+// transactionHashesOnBC - Returns the hashes of every transaction on
+// the blockchain, so nothing already in a block gets in again
+func (bc *Blockchain) transactionHashesOnBC() map[string]bool {
+	hashes := make(map[string]bool)
+	for i := range *bc {
+		for j := range (*bc)[i].TXs {
+			hashes[string((*bc)[i].TXs[j].HashTransaction())] = true
+		}
+	}
+	return hashes
+}
+
+// checkBlock - Checks a block as the next block on top of the
+// blockchain. The transactions in txpool count towards the balances
+// the same way the transactions before each one in the block do.
+// Returns why the block is invalid, or an empty string if it's valid
+func (bc Blockchain) checkBlock(b *Block, txpool []Transaction) string {
+	height := uint64(len(bc))
+
+	if b.Index != height {
+		return "index doesn't match its height"
+	}
+	if bytes.Compare(b.HashBlock(), b.Hash) != 0 {
+		return "hash doesn't match its contents"
+	}
+	if !consensus.VerifySeal(&bc, b) {
+		return "invalid seal"
+	}
+	if !bc.BlockVersionIsValid(b) {
+		return "version signals for deployments it can't"
+	}
+	if height > 0 && bytes.Compare(b.PrevHash, bc[height-1].Hash) != 0 {
+		return "previous hash doesn't match the block below"
+	}
+	if height > 0 && b.Timestamp <= bc.MedianTimePast() {
+		return "timestamp isn't after the median time past"
+	}
+	if b.Timestamp > uint64(time.Now().Unix())+MaxFutureBlockTime {
+		return "timestamp too far in the future"
+	}
+	rules := bc.ActiveRuleSet(height)
+	if !rules.BlockSizeIsValid(b) {
+		return "too many transactions for rule set " + rules.Name
+	}
+	if bytes.Compare(CalcMerkleRoot(b.TXs), b.MerkleRoot) != 0 {
+		return "Merkle root doesn't match the transactions"
+	}
+	if bytes.Compare(bc.CalcStateRoot(b), b.StateRoot) != 0 {
+		return "state root doesn't match the contract state"
+	}
+
+	// Every transaction gets checked with the transactions before
+	// it in the block counting towards the balance of its input.
+	// The genesis block hands out the first coins, so nothing in
+	// it has to be paid for. The signatures get checked in
+	// parallel up front, which leaves the valid ones cached
+	// A transaction can only go in once, so none of them can be on
+	// the blockchain, in txpool or earlier in the block already
+	VerifySignatures(b.TXs)
+	known := bc.transactionHashesOnBC()
+	for i := range txpool {
+		known[string(txpool[i].HashTransaction())] = true
+	}
+	pending := append([]Transaction{}, txpool...)
+	for i := range b.TXs {
+		tx := &b.TXs[i]
+		hash := string(tx.HashTransaction())
+		reason := ""
+		switch {
+		case known[hash]:
+			reason = "already went in"
+		case !tx.signatureIsValidCached():
+			reason = "invalid signature"
+		case height > 0 && !tx.TransactionCostIsValid(&bc, pending, -1):
+			reason = "input can't pay for it"
+		case !tx.TransactionIsFinal(b.Index, b.Timestamp):
+			reason = "still time-locked"
+		case !tx.TransactionTypeIsValid(&bc, pending):
+			reason = "breaks the rules of its type"
+		case tx.htlcSettledIn(pending):
+			reason = "settles an HTLC that's already settled"
+		}
+		if reason != "" {
+			return "transaction " + strconv.Itoa(i) + ": " + reason
+		}
+		known[hash] = true
+		pending = append(pending, *tx)
+	}
+
+	return ""
+}
@@ -0,0 +1,79 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFundedSpendAndOverdraft(t *testing.T) {
+	treasury := testKey(t)
+	alice := testKey(t)
+	bob := testKey(t)
+	bc := genesisChain(t, transfer(t, treasury, &alice.PublicKey, 10))
+
+	funded := nextBlock(t, &bc, transfer(t, alice, &bob.PublicKey, 4))
+	if !bc.AddBlock(&funded) {
+		t.Fatal("funded spend rejected")
+	}
+	if balance := bc.CalcAccountBalanceOnBC(&alice.PublicKey, -1); balance != 6 {
+		t.Fatalf("balance after the spend = %v, want 6", balance)
+	}
+
+	overdraft := nextBlock(t, &bc, transfer(t, bob, &alice.PublicKey, 5))
+	if bc.AddBlock(&overdraft) {
+		t.Fatal("overdraft accepted")
+	}
+
+	// Two spends that each fit in the balance but not together
+	double := nextBlock(t, &bc,
+		transfer(t, alice, &bob.PublicKey, 4),
+		transfer(t, alice, &bob.PublicKey, 4))
+	if bc.AddBlock(&double) {
+		t.Fatal("spends of the same coins in one block accepted")
+	}
+	if len(bc) != 2 {
+		t.Fatalf("chain length = %d, want 2", len(bc))
+	}
+
+	if err := bc.VerifyChain(0, -1); err != nil {
+		t.Fatal(err)
+	}
+	bad := append(bc, overdraft)
+	var verr *VerifyError
+	if err := bad.VerifyChain(0, -1); !errors.As(err, &verr) || verr.Height != 2 {
+		t.Fatalf("VerifyChain = %v, want a failure at height 2", err)
+	}
+}
+
+func TestBlockTimestamp(t *testing.T) {
+	bc := genesisChain(t)
+	for len(bc) < MedianTimeSpan+1 {
+		b := nextBlock(t, &bc)
+		if !bc.AddBlock(&b) {
+			t.Fatalf("block %d rejected", len(bc))
+		}
+	}
+
+	seal := func(timestamp uint64) *Block {
+		b := Block{Timestamp: timestamp}
+		if err := bc.SealBlock(&b); err != nil {
+			t.Fatal(err)
+		}
+		return &b
+	}
+	mtp := bc.MedianTimePast()
+	if mtp != bc[len(bc)-1-MedianTimeSpan/2].Timestamp {
+		t.Fatalf("median time past = %d", mtp)
+	}
+	if bc.BlockIsValid(seal(mtp), nil) {
+		t.Fatal("block at the median time past accepted")
+	}
+	if !bc.BlockIsValid(seal(mtp+1), nil) {
+		t.Fatal("block right after the median time past rejected")
+	}
+	future := uint64(time.Now().Unix()) + MaxFutureBlockTime + 60
+	if bc.BlockIsValid(seal(future), nil) {
+		t.Fatal("block from the future accepted")
+	}
+}
@@ -0,0 +1,207 @@
+package blockchain
+
+import "sync"
+
+const (
+	// VersionBitsTopBits - The top three bits of the version of a
+	// block that signals readiness for deployments
+	VersionBitsTopBits = 0x20000000
+
+	// VersionBitsTopMask - Selects the top three bits of a version
+	VersionBitsTopMask = 0xe0000000
+
+	// MaxDeploymentBit - Highest bit a deployment can signal on
+	MaxDeploymentBit = 28
+)
+
+// States a deployment goes through. Every deployment starts out
+// defined, and its state can only change at the start of a
+// signaling window
+const (
+	// DeploymentDefined - The deployment hasn't started yet
+	DeploymentDefined = 0
+
+	// DeploymentStarted - Blocks can signal for the deployment
+	DeploymentStarted = 1
+
+	// DeploymentLockedIn - Enough blocks of a window signaled, and
+	// the deployment will activate at the start of a later window
+	DeploymentLockedIn = 2
+
+	// DeploymentActive - The rules of the deployment are enforced
+	DeploymentActive = 3
+
+	// DeploymentFailed - The deployment timed out before enough
+	// blocks signaled for it
+	DeploymentFailed = 4
+)
+
+// Deployment - A soft fork rolled out through version bits. Blocks
+// signal for it by setting Bit in their version from StartHeight on.
+// Once ActivationThreshold blocks of a signaling window do, it locks
+// in, and it activates at the first window starting at or after
+// MinActivationHeight. It fails if it hasn't locked in by
+// TimeoutHeight
+type Deployment struct {
+	Name                string `json:"Name"`
+	Bit                 uint8  `json:"Bit"`
+	StartHeight         uint64 `json:"StartHeight"`
+	TimeoutHeight       uint64 `json:"TimeoutHeight"`
+	MinActivationHeight uint64 `json:"MinActivationHeight"`
+}
+
+// SignalsDeployment - Returns true if the version of the block
+// signals for the deployment. HashFormatV1 doesn't cover the
+// version, so anyone relaying such a block could change it, and
+// only blocks hashed with HashFormatV2 count
+func (b *Block) SignalsDeployment(d *Deployment) bool {
+	if RuleSetAt(b.Index).HashFormat != HashFormatV2 {
+		return false
+	}
+	return b.Version&VersionBitsTopMask == VersionBitsTopBits && b.Version&(1<<d.Bit) != 0
+}
+
+// findDeployment - Returns the deployment of the chain parameters
+// with the given name, or nil
+func findDeployment(name string) *Deployment {
+	for i := range params.Deployments {
+		if params.Deployments[i].Name == name {
+			return &params.Deployments[i]
+		}
+	}
+	return nil
+}
+
+// deploymentCache - Deployment states at the start of signaling
+// windows, keyed by the deployment and the hash of the last block
+// before the window. That block commits to every block below it,
+// so a reorganization can't hit a stale entry
+var deploymentCache = make(map[string]int)
+var deploymentCacheMux sync.Mutex
+
+// deploymentCacheKey - Returns the key of the state of a deployment
+// for the window starting at the given height, or an empty string
+// if the block before the window isn't on the chain yet
+func (bc *Blockchain) deploymentCacheKey(d *Deployment, start uint64) string {
+	if start == 0 || start > uint64(len(*bc)) || len((*bc)[start-1].Hash) == 0 {
+		return ""
+	}
+	return d.Name + "/" + string((*bc)[start-1].Hash)
+}
+
+// resetDeploymentCache - Forgets every cached deployment state.
+// Called whenever the chain parameters change
+func resetDeploymentCache() {
+	deploymentCacheMux.Lock()
+	defer deploymentCacheMux.Unlock()
+	deploymentCache = make(map[string]int)
+}
+
+// DeploymentState - Returns the state a deployment is in for the block
+// at the given height, which only depends on the blocks of the
+// signaling windows before it. The state at the start of every
+// window gets cached, so only the windows since the last cached
+// one have to be counted
+func (bc *Blockchain) DeploymentState(name string, height uint64) int {
+	d := findDeployment(name)
+	window := params.SignalingWindow
+	if d == nil || window == 0 || d.Bit > MaxDeploymentBit {
+		return DeploymentFailed
+	}
+
+	// Start from the latest window with a cached state
+	state := DeploymentDefined
+	var start uint64 = 0
+	deploymentCacheMux.Lock()
+	for next := height - height%window; next > 0; next -= window {
+		if cached, ok := deploymentCache[bc.deploymentCacheKey(d, next)]; ok {
+			state = cached
+			start = next
+			break
+		}
+	}
+	deploymentCacheMux.Unlock()
+
+	for ; start+window <= height; start += window {
+		// The state for the window starting at next depends on
+		// the window that just ended
+		next := start + window
+		switch state {
+		case DeploymentDefined:
+			if next >= d.TimeoutHeight {
+				state = DeploymentFailed
+			} else if next >= d.StartHeight {
+				state = DeploymentStarted
+			}
+		case DeploymentStarted:
+			var signals uint64 = 0
+			for i := start; i < next && i < uint64(len(*bc)); i++ {
+				if (*bc)[i].SignalsDeployment(d) {
+					signals++
+				}
+			}
+			if signals >= params.ActivationThreshold {
+				state = DeploymentLockedIn
+			} else if next >= d.TimeoutHeight {
+				state = DeploymentFailed
+			}
+		case DeploymentLockedIn:
+			if next >= d.MinActivationHeight {
+				state = DeploymentActive
+			}
+		}
+
+		if key := bc.deploymentCacheKey(d, next); key != "" {
+			deploymentCacheMux.Lock()
+			deploymentCache[key] = state
+			deploymentCacheMux.Unlock()
+		}
+	}
+	return state
+}
+
+// DeploymentIsActive - Returns true if the rules of a deployment
+// apply to the block at the given height. ActiveRuleSet checks
+// this for the rule sets gated on a deployment
+func (bc *Blockchain) DeploymentIsActive(name string, height uint64) bool {
+	return bc.DeploymentState(name, height) == DeploymentActive
+}
+
+// signalBits - Returns the bits of every deployment the block at the
+// given height can signal for: the ones that are started or locked
+// in. Blocks hashed with HashFormatV1 can't signal
+func (bc *Blockchain) signalBits(height uint64) uint32 {
+	var bits uint32 = 0
+	if RuleSetAt(height).HashFormat != HashFormatV2 {
+		return bits
+	}
+	for i := range params.Deployments {
+		d := &params.Deployments[i]
+		if d.Bit > MaxDeploymentBit {
+			continue
+		}
+		state := bc.DeploymentState(d.Name, height)
+		if state == DeploymentStarted || state == DeploymentLockedIn {
+			bits |= 1 << d.Bit
+		}
+	}
+	return bits
+}
+
+// CalcBlockVersion - Returns the version the next block on top of the
+// blockchain should have: the version bits top bits, plus the bit of
+// every deployment that is started or locked in
+func (bc *Blockchain) CalcBlockVersion() uint32 {
+	return VersionBitsTopBits | bc.signalBits(uint64(len(*bc)))
+}
+
+// BlockVersionIsValid - Checks the version of a block on top of the
+// blockchain: it needs the version bits top bits, and every other bit
+// it sets has to belong to a deployment it can signal for. Blocks
+// don't have to signal for every deployment they can
+func (bc *Blockchain) BlockVersionIsValid(b *Block) bool {
+	if b.Version&VersionBitsTopMask != VersionBitsTopBits {
+		return false
+	}
+	return b.Version&^VersionBitsTopMask&^bc.signalBits(uint64(len(*bc))) == 0
+}
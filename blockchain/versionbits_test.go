@@ -0,0 +1,257 @@
+package blockchain
+
+import "testing"
+
+// versionBitsParams - Chain parameters with small signaling windows
+// and one deployment on bit 1
+func versionBitsParams() ChainParams {
+	p := DefaultChainParams()
+	p.SignalingWindow = 10
+	p.ActivationThreshold = 8
+	p.Deployments = []Deployment{{Name: "test", Bit: 1, StartHeight: 10, TimeoutHeight: 100, MinActivationHeight: 0}}
+	return p
+}
+
+// signalingChain - Returns a chain of n blocks where the blocks
+// signal for bit 1 if signals returns true for their height
+func signalingChain(n uint64, signals func(height uint64) bool) Blockchain {
+	var bc Blockchain
+	for i := uint64(0); i < n; i++ {
+		version := uint32(VersionBitsTopBits)
+		if signals(i) {
+			version |= 1 << 1
+		}
+		bc = append(bc, Block{Index: i, Version: version})
+	}
+	return bc
+}
+
+func TestDeploymentStateTransitions(t *testing.T) {
+	defer SetChainParams(ActiveChainParams())
+	SetChainParams(versionBitsParams())
+
+	// 8 of the blocks 10 to 19 signal
+	bc := signalingChain(40, func(h uint64) bool { return h >= 10 && h < 18 })
+	tests := []struct {
+		height uint64
+		state  int
+	}{
+		{0, DeploymentDefined},
+		{9, DeploymentDefined},
+		{10, DeploymentStarted},
+		{19, DeploymentStarted},
+		{20, DeploymentLockedIn},
+		{29, DeploymentLockedIn},
+		{30, DeploymentActive},
+		{40, DeploymentActive},
+	}
+	for _, test := range tests {
+		if state := bc.DeploymentState("test", test.height); state != test.state {
+			t.Fatalf("state at %d = %d, want %d", test.height, state, test.state)
+		}
+	}
+	if bc.DeploymentIsActive("test", 29) || !bc.DeploymentIsActive("test", 30) {
+		t.Fatal("DeploymentIsActive disagrees with DeploymentState")
+	}
+	if bc.DeploymentState("unknown", 30) != DeploymentFailed {
+		t.Fatal("unknown deployments should fail")
+	}
+}
+
+func TestDeploymentThresholdTimeoutAndMinActivation(t *testing.T) {
+	defer SetChainParams(ActiveChainParams())
+	p := versionBitsParams()
+	SetChainParams(p)
+
+	// 7 signals in a window isn't enough, and the deployment
+	// times out at height 100
+	bc := signalingChain(120, func(h uint64) bool { return h%10 < 7 })
+	if state := bc.DeploymentState("test", 99); state != DeploymentStarted {
+		t.Fatalf("state before the timeout = %d", state)
+	}
+	if state := bc.DeploymentState("test", 100); state != DeploymentFailed {
+		t.Fatalf("state at the timeout = %d", state)
+	}
+
+	// Locking in during the last window before the timeout
+	// still counts
+	bc = signalingChain(120, func(h uint64) bool { return h >= 90 })
+	if state := bc.DeploymentState("test", 100); state != DeploymentLockedIn {
+		t.Fatalf("lock-in in the last window = %d", state)
+	}
+
+	// Activation waits for MinActivationHeight
+	p.Deployments[0].MinActivationHeight = 50
+	SetChainParams(p)
+	bc = signalingChain(60, func(h uint64) bool { return h >= 10 && h < 20 })
+	if state := bc.DeploymentState("test", 40); state != DeploymentLockedIn {
+		t.Fatalf("activated before MinActivationHeight: %d", state)
+	}
+	if state := bc.DeploymentState("test", 50); state != DeploymentActive {
+		t.Fatalf("state at MinActivationHeight = %d", state)
+	}
+}
+
+func TestCalcBlockVersion(t *testing.T) {
+	defer SetChainParams(ActiveChainParams())
+	SetChainParams(versionBitsParams())
+
+	bc := signalingChain(10, func(h uint64) bool { return false })
+	if version := bc.CalcBlockVersion(); version != VersionBitsTopBits|1<<1 {
+		t.Fatalf("started deployment not signaled: %x", version)
+	}
+	if !(&Block{Version: bc.CalcBlockVersion()}).SignalsDeployment(&ActiveChainParams().Deployments[0]) {
+		t.Fatal("SignalsDeployment disagrees with CalcBlockVersion")
+	}
+
+	bc = signalingChain(5, func(h uint64) bool { return false })
+	if version := bc.CalcBlockVersion(); version != VersionBitsTopBits {
+		t.Fatalf("defined deployment signaled: %x", version)
+	}
+
+	// Without the top bits, a version doesn't signal anything
+	if (&Block{Version: 1 << 1}).SignalsDeployment(&ActiveChainParams().Deployments[0]) {
+		t.Fatal("version without the top bits signals")
+	}
+}
+
+func TestRuleSetGatedOnDeployment(t *testing.T) {
+	defer SetChainParams(ActiveChainParams())
+	p := versionBitsParams()
+	p.RuleSets = []RuleSet{
+		{Name: "v2", HashFormat: HashFormatV2},
+		{Name: "gated", ActivationHeight: 5, Deployment: "test", HashFormat: HashFormatV1, MaxTXDataSize: 4},
+	}
+	SetChainParams(p)
+
+	bc := signalingChain(40, func(h uint64) bool { return h >= 10 && h < 20 })
+	large := &Transaction{Type: TXTransfer, Data: []byte("12345")}
+
+	before := bc[:29]
+	if rules := before.ActiveRuleSet(29); rules.Name != "v2" {
+		t.Fatalf("gated rule set active before its deployment: %s", rules.Name)
+	}
+	if !large.TransactionTypeIsValid(&before, nil) {
+		t.Fatal("size limit enforced before the deployment is active")
+	}
+
+	after := bc[:30]
+	rules := after.ActiveRuleSet(30)
+	if rules.Name != "gated" {
+		t.Fatalf("gated rule set not active once its deployment is: %s", rules.Name)
+	}
+	if rules.HashFormat != HashFormatV2 {
+		t.Fatal("a gated rule set changed the hash format")
+	}
+	if large.TransactionTypeIsValid(&after, nil) {
+		t.Fatal("size limit of the gated rule set not enforced")
+	}
+	if RuleSetAt(30).Name != "v2" {
+		t.Fatal("RuleSetAt should skip gated rule sets")
+	}
+}
+
+// versionedBlock - Returns a block on top of the chain with the
+// given version, sealed after the version is set
+func versionedBlock(t *testing.T, bc *Blockchain, version uint32) Block {
+	t.Helper()
+	b := Block{Timestamp: 1700000000 + uint64(len(*bc))}
+	if err := bc.SealBlock(&b); err != nil {
+		t.Fatal(err)
+	}
+	b.Version = version
+	if err := CurrentConsensusEngine().Seal(bc, &b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestBlockVersionIsValid(t *testing.T) {
+	defer SetChainParams(ActiveChainParams())
+	SetChainParams(versionBitsParams())
+
+	bc := extend(t, genesisChain(t), 8)
+	signal := uint32(VersionBitsTopBits | 1<<1)
+	early := versionedBlock(t, &bc, signal)
+	if bc.AddBlock(&early) {
+		t.Fatal("signal for a deployment that hasn't started accepted")
+	}
+	noTopBits := versionedBlock(t, &bc, 0)
+	if bc.AddBlock(&noTopBits) {
+		t.Fatal("version without the top bits accepted")
+	}
+	bc = extend(t, bc, 1)
+
+	for _, version := range []uint32{signal, VersionBitsTopBits} {
+		b := versionedBlock(t, &bc, version)
+		if !bc.AddBlock(&b) {
+			t.Fatalf("version %x rejected once the deployment started", version)
+		}
+	}
+	unknown := versionedBlock(t, &bc, signal|1<<5)
+	if bc.AddBlock(&unknown) {
+		t.Fatal("signal for an unknown deployment accepted")
+	}
+
+	// The version is covered by the hash, so relaying a block with
+	// its signal flipped breaks it
+	flipped := versionedBlock(t, &bc, signal)
+	flipped.Version = VersionBitsTopBits
+	if bc.AddBlock(&flipped) {
+		t.Fatal("block with a changed version accepted")
+	}
+
+	// HashFormatV1 doesn't cover the version, so blocks hashed
+	// with it can't signal
+	p := versionBitsParams()
+	p.RuleSets = []RuleSet{{Name: "original", HashFormat: HashFormatV1}}
+	SetChainParams(p)
+	v1 := extend(t, genesisChain(t), 9)
+	if version := v1.CalcBlockVersion(); version != VersionBitsTopBits {
+		t.Fatalf("HashFormatV1 block version = %x", version)
+	}
+	b := versionedBlock(t, &v1, signal)
+	if v1.AddBlock(&b) {
+		t.Fatal("HashFormatV1 block signaling accepted")
+	}
+}
+
+func TestDeploymentStateCache(t *testing.T) {
+	defer SetChainParams(ActiveChainParams())
+	SetChainParams(versionBitsParams())
+
+	// Sealed blocks signal for every started deployment
+	bc := extend(t, genesisChain(t), 34)
+
+	// A fork from height 10 where nothing signals
+	fork := append(Blockchain{}, bc[:10]...)
+	for len(fork) < 35 {
+		b := versionedBlock(t, &fork, VersionBitsTopBits)
+		fork = append(fork, b)
+	}
+
+	// Blocks without hashes never get cached
+	uncached := func(chain Blockchain) Blockchain {
+		c := make(Blockchain, len(chain))
+		for i := range chain {
+			c[i] = Block{Index: chain[i].Index, Version: chain[i].Version}
+		}
+		return c
+	}
+
+	for i := 0; i < 2; i++ {
+		for height := uint64(0); height <= 35; height++ {
+			want := uncached(bc)
+			if state := bc.DeploymentState("test", height); state != want.DeploymentState("test", height) {
+				t.Fatalf("cached state at %d = %d", height, state)
+			}
+			want = uncached(fork)
+			if state := fork.DeploymentState("test", height); state != want.DeploymentState("test", height) {
+				t.Fatalf("cached state of the fork at %d = %d", height, state)
+			}
+		}
+	}
+	if !bc.DeploymentIsActive("test", 30) || fork.DeploymentState("test", 30) != DeploymentStarted {
+		t.Fatal("the fork shares the cached states of the chain")
+	}
+}
@@ -0,0 +1,341 @@
+package compute
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// VM opcodes. PUSH1 to PUSH32 push the 1 to 32 bytes that follow
+// them. Every stack word is a 256 bit unsigned integer
+const (
+	OpStop      = 0x00
+	OpAdd       = 0x01
+	OpSub       = 0x02
+	OpMul       = 0x03
+	OpDiv       = 0x04
+	OpMod       = 0x05
+	OpLt        = 0x10
+	OpGt        = 0x11
+	OpEq        = 0x12
+	OpIsZero    = 0x13
+	OpAnd       = 0x16
+	OpOr        = 0x17
+	OpSHA256    = 0x20
+	OpCaller    = 0x33
+	OpCallValue = 0x34
+	OpInput     = 0x35
+	OpInputSize = 0x36
+	OpPop       = 0x50
+	OpSLoad     = 0x54
+	OpSStore    = 0x55
+	OpJump      = 0x56
+	OpJumpI     = 0x57
+	OpJumpDest  = 0x5b
+	OpPush1     = 0x60
+	OpPush32    = 0x7f
+	OpDup1      = 0x80
+	OpSwap1     = 0x90
+	OpReturn    = 0xf3
+	OpRevert    = 0xfd
+)
+
+// Gas costs of the opcodes. Anything not listed costs GasBase
+const (
+	GasBase   = 1
+	GasJump   = 8
+	GasSHA256 = 30
+	GasSLoad  = 50
+	GasSStore = 200
+)
+
+const (
+	// MaxVMStackSize - Maximum number of words on the stack
+	MaxVMStackSize = 1024
+
+	// WordSize - Size of a stack word (in bytes)
+	WordSize = 32
+)
+
+var (
+	// ErrOutOfGas - Execution ran out of gas
+	ErrOutOfGas = errors.New("Execute: out of gas")
+
+	// ErrRevert - The contract ran REVERT
+	ErrRevert = errors.New("Execute: reverted")
+
+	// wordModulus - Arithmetic wraps around at 2^256
+	wordModulus = new(big.Int).Lsh(big.NewInt(1), WordSize*8)
+)
+
+// Contract - The code of a contract and its key-value storage
+type Contract struct {
+	Code    []byte            `json:"Code"`
+	Storage map[string][]byte `json:"Storage"`
+}
+
+// CallContext - Everything about a call the contract gets to see
+// besides its own storage
+type CallContext struct {
+	Caller   []byte // 32 byte identifier of the caller
+	Value    uint64 // Value sent along with the call
+	Input    []byte
+	GasLimit uint64
+}
+
+// MakeContract - Contract constructor
+func MakeContract(code []byte) *Contract {
+	return &Contract{Code: code, Storage: make(map[string][]byte)}
+}
+
+// toWord - Left-pads a value to a full word
+func toWord(b []byte) []byte {
+	word := make([]byte, WordSize)
+	if len(b) > WordSize {
+		b = b[len(b)-WordSize:]
+	}
+	copy(word[WordSize-len(b):], b)
+	return word
+}
+
+// fromInt - Converts a number into a word, wrapping around at 2^256
+func fromInt(n *big.Int) []byte {
+	return toWord(new(big.Int).Mod(n, wordModulus).Bytes())
+}
+
+// validJumpDests - Returns the positions of every JUMPDEST that
+// isn't inside the data of a PUSH
+func validJumpDests(code []byte) map[int]bool {
+	dests := make(map[int]bool)
+	for pc := 0; pc < len(code); pc++ {
+		op := code[pc]
+		if op >= OpPush1 && op <= OpPush32 {
+			pc += int(op-OpPush1) + 1
+		} else if op == OpJumpDest {
+			dests[pc] = true
+		}
+	}
+	return dests
+}
+
+// gasCost - Returns how much gas an opcode costs
+func gasCost(op byte) uint64 {
+	switch op {
+	case OpJump, OpJumpI:
+		return GasJump
+	case OpSHA256:
+		return GasSHA256
+	case OpSLoad:
+		return GasSLoad
+	case OpSStore:
+		return GasSStore
+	}
+	return GasBase
+}
+
+// Execute - Runs the code of a contract. Storage writes only reach the
+// contract if execution succeeds, so a failed call leaves it as it
+// was. Returns whatever RETURN returned and how much gas was used.
+// Execution is deterministic: the same contract and context always
+// give the same result
+func (c *Contract) Execute(ctx CallContext) ([]byte, uint64, error) {
+	var gasUsed uint64 = 0
+	var stack [][]byte
+	writes := make(map[string][]byte)
+	dests := validJumpDests(c.Code)
+
+	// pop - Removes the top n words of the stack, top first
+	pop := func(n int) ([]*big.Int, error) {
+		if len(stack) < n {
+			return nil, errors.New("Execute: stack underflow")
+		}
+		words := make([]*big.Int, n)
+		for i := 0; i < n; i++ {
+			words[i] = new(big.Int).SetBytes(stack[len(stack)-1-i])
+		}
+		stack = stack[:len(stack)-n]
+		return words, nil
+	}
+
+	// load - Reads storage, seeing the writes of this call first
+	load := func(key string) []byte {
+		if v, ok := writes[key]; ok {
+			return v
+		}
+		if v, ok := c.Storage[key]; ok {
+			return v
+		}
+		return toWord(nil)
+	}
+
+	pc := 0
+	for pc < len(c.Code) {
+		op := c.Code[pc]
+		pc++
+
+		gasUsed += gasCost(op)
+		if gasUsed > ctx.GasLimit {
+			return nil, ctx.GasLimit, ErrOutOfGas
+		}
+
+		switch {
+		case op >= OpPush1 && op <= OpPush32:
+			n := int(op-OpPush1) + 1
+			if pc+n > len(c.Code) {
+				return nil, gasUsed, errors.New("Execute: truncated push")
+			}
+			stack = append(stack, toWord(c.Code[pc:pc+n]))
+			pc += n
+		case op == OpStop:
+			c.commit(writes)
+			return nil, gasUsed, nil
+		case op == OpAdd || op == OpSub || op == OpMul || op == OpDiv || op == OpMod ||
+			op == OpLt || op == OpGt || op == OpEq || op == OpAnd || op == OpOr:
+			w, err := pop(2)
+			if err != nil {
+				return nil, gasUsed, err
+			}
+			a, b := w[0], w[1]
+			r := new(big.Int)
+			switch op {
+			case OpAdd:
+				r.Add(a, b)
+			case OpSub:
+				r.Sub(a, b)
+			case OpMul:
+				r.Mul(a, b)
+			case OpDiv:
+				if b.Sign() != 0 {
+					r.Div(a, b)
+				}
+			case OpMod:
+				if b.Sign() != 0 {
+					r.Mod(a, b)
+				}
+			case OpLt:
+				if a.Cmp(b) < 0 {
+					r.SetInt64(1)
+				}
+			case OpGt:
+				if a.Cmp(b) > 0 {
+					r.SetInt64(1)
+				}
+			case OpEq:
+				if a.Cmp(b) == 0 {
+					r.SetInt64(1)
+				}
+			case OpAnd:
+				r.And(a, b)
+			case OpOr:
+				r.Or(a, b)
+			}
+			stack = append(stack, fromInt(r))
+		case op == OpIsZero:
+			w, err := pop(1)
+			if err != nil {
+				return nil, gasUsed, err
+			}
+			r := new(big.Int)
+			if w[0].Sign() == 0 {
+				r.SetInt64(1)
+			}
+			stack = append(stack, fromInt(r))
+		case op == OpSHA256:
+			w, err := pop(1)
+			if err != nil {
+				return nil, gasUsed, err
+			}
+			hash := sha256.Sum256(fromInt(w[0]))
+			stack = append(stack, hash[:])
+		case op == OpCaller:
+			stack = append(stack, toWord(ctx.Caller))
+		case op == OpCallValue:
+			stack = append(stack, fromInt(new(big.Int).SetUint64(ctx.Value)))
+		case op == OpInputSize:
+			stack = append(stack, fromInt(big.NewInt(int64(len(ctx.Input)))))
+		case op == OpInput:
+			// Pushes the word of the input starting at the offset,
+			// padded with zeros past the end of the input
+			w, err := pop(1)
+			if err != nil {
+				return nil, gasUsed, err
+			}
+			word := make([]byte, WordSize)
+			if w[0].IsInt64() && w[0].Int64() < int64(len(ctx.Input)) {
+				copy(word, ctx.Input[w[0].Int64():])
+			}
+			stack = append(stack, word)
+		case op == OpPop:
+			if _, err := pop(1); err != nil {
+				return nil, gasUsed, err
+			}
+		case op == OpSLoad:
+			w, err := pop(1)
+			if err != nil {
+				return nil, gasUsed, err
+			}
+			stack = append(stack, load(string(fromInt(w[0]))))
+		case op == OpSStore:
+			// The key is on top, the value below it
+			w, err := pop(2)
+			if err != nil {
+				return nil, gasUsed, err
+			}
+			writes[string(fromInt(w[0]))] = fromInt(w[1])
+		case op == OpJump || op == OpJumpI:
+			n := 1
+			if op == OpJumpI {
+				n = 2
+			}
+			// The destination is on top, the condition below it
+			w, err := pop(n)
+			if err != nil {
+				return nil, gasUsed, err
+			}
+			if op == OpJumpI && w[1].Sign() == 0 {
+				break
+			}
+			if !w[0].IsInt64() || !dests[int(w[0].Int64())] {
+				return nil, gasUsed, errors.New("Execute: invalid jump destination")
+			}
+			pc = int(w[0].Int64())
+		case op == OpJumpDest:
+		case op == OpDup1:
+			if len(stack) < 1 {
+				return nil, gasUsed, errors.New("Execute: stack underflow")
+			}
+			stack = append(stack, stack[len(stack)-1])
+		case op == OpSwap1:
+			if len(stack) < 2 {
+				return nil, gasUsed, errors.New("Execute: stack underflow")
+			}
+			stack[len(stack)-1], stack[len(stack)-2] = stack[len(stack)-2], stack[len(stack)-1]
+		case op == OpReturn:
+			w, err := pop(1)
+			if err != nil {
+				return nil, gasUsed, err
+			}
+			c.commit(writes)
+			return fromInt(w[0]), gasUsed, nil
+		case op == OpRevert:
+			return nil, gasUsed, ErrRevert
+		default:
+			return nil, gasUsed, errors.New("Execute: unknown opcode")
+		}
+
+		if len(stack) > MaxVMStackSize {
+			return nil, gasUsed, errors.New("Execute: stack overflow")
+		}
+	}
+
+	// Running off the end of the code is the same as STOP
+	c.commit(writes)
+	return nil, gasUsed, nil
+}
+
+// commit - Writes the storage changes of a successful call
+func (c *Contract) commit(writes map[string][]byte) {
+	for k, v := range writes {
+		c.Storage[k] = v
+	}
+}
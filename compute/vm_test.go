@@ -0,0 +1,154 @@
+package compute
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+// run - Executes code on a fresh contract and returns the number
+// it returned
+func run(t *testing.T, code []byte, ctx CallContext) *big.Int {
+	t.Helper()
+	if ctx.GasLimit == 0 {
+		ctx.GasLimit = 10000
+	}
+	ret, _, err := MakeContract(code).Execute(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return new(big.Int).SetBytes(ret)
+}
+
+func TestArithmeticOpcodes(t *testing.T) {
+	max := new(big.Int).Sub(wordModulus, big.NewInt(1))
+	tests := []struct {
+		name string
+		op   byte
+		a, b int64 // a on top of the stack, b below it
+		want *big.Int
+	}{
+		{"add", OpAdd, 2, 3, big.NewInt(5)},
+		{"sub", OpSub, 7, 3, big.NewInt(4)},
+		{"sub wraps", OpSub, 0, 1, max},
+		{"mul", OpMul, 6, 7, big.NewInt(42)},
+		{"div", OpDiv, 7, 2, big.NewInt(3)},
+		{"div by zero", OpDiv, 7, 0, big.NewInt(0)},
+		{"mod", OpMod, 7, 4, big.NewInt(3)},
+		{"mod by zero", OpMod, 7, 0, big.NewInt(0)},
+		{"lt", OpLt, 1, 2, big.NewInt(1)},
+		{"gt", OpGt, 1, 2, big.NewInt(0)},
+		{"eq", OpEq, 9, 9, big.NewInt(1)},
+		{"and", OpAnd, 12, 10, big.NewInt(8)},
+		{"or", OpOr, 12, 10, big.NewInt(14)},
+	}
+	for _, test := range tests {
+		code := []byte{OpPush1, byte(test.b), OpPush1, byte(test.a), test.op, OpReturn}
+		if got := run(t, code, CallContext{}); got.Cmp(test.want) != 0 {
+			t.Fatalf("%s = %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	if got := run(t, []byte{OpPush1, 0, OpIsZero, OpReturn}, CallContext{}); got.Int64() != 1 {
+		t.Fatal("ISZERO of zero isn't one")
+	}
+	if got := run(t, []byte{OpPush1, 1, OpPush1, 2, OpSwap1, OpPop, OpDup1, OpAdd, OpReturn}, CallContext{}); got.Int64() != 4 {
+		t.Fatalf("SWAP1/POP/DUP1 = %v, want 4", got)
+	}
+}
+
+func TestContextOpcodes(t *testing.T) {
+	caller := bytes.Repeat([]byte{7}, 32)
+	ctx := CallContext{Caller: caller, Value: 500, Input: []byte{1, 2, 3}}
+	if got := run(t, []byte{OpCaller, OpReturn}, ctx); bytes.Compare(toWord(got.Bytes()), caller) != 0 {
+		t.Fatal("CALLER doesn't push the caller")
+	}
+	if got := run(t, []byte{OpCallValue, OpReturn}, ctx); got.Int64() != 500 {
+		t.Fatalf("CALLVALUE = %v", got)
+	}
+	if got := run(t, []byte{OpInputSize, OpReturn}, ctx); got.Int64() != 3 {
+		t.Fatalf("INPUTSIZE = %v", got)
+	}
+	want := new(big.Int).SetBytes(append([]byte{2, 3}, make([]byte, 30)...))
+	if got := run(t, []byte{OpPush1, 1, OpInput, OpReturn}, ctx); got.Cmp(want) != 0 {
+		t.Fatalf("INPUT = %x", got)
+	}
+}
+
+func TestJumpsAndStorage(t *testing.T) {
+	// Adds 5+4+3+2+1 into storage slot 0, with the counter on
+	// the stack
+	code := []byte{
+		OpPush1, 5,
+		OpJumpDest, // 2: loop
+		OpDup1, OpIsZero, OpPush1, 23, OpJumpI,
+		OpDup1, OpPush1, 0, OpSLoad, OpAdd, OpPush1, 0, OpSStore,
+		OpPush1, 1, OpSwap1, OpSub,
+		OpPush1, 2, OpJump,
+		OpJumpDest, // 23: done
+		OpPush1, 0, OpSLoad, OpReturn,
+	}
+	contract := MakeContract(code)
+	ret, gasUsed, err := contract.Execute(CallContext{GasLimit: 10000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if new(big.Int).SetBytes(ret).Int64() != 15 {
+		t.Fatalf("loop returned %x, want 15", ret)
+	}
+	if stored := contract.Storage[string(toWord(nil))]; new(big.Int).SetBytes(stored).Int64() != 15 {
+		t.Fatal("storage write not committed")
+	}
+
+	// Execution is deterministic, gas included
+	again := MakeContract(code)
+	ret2, gasUsed2, _ := again.Execute(CallContext{GasLimit: 10000})
+	if bytes.Compare(ret, ret2) != 0 || gasUsed != gasUsed2 {
+		t.Fatal("the same call gave different results")
+	}
+
+	// A JUMPDEST inside the data of a PUSH isn't a destination
+	if _, _, err := MakeContract([]byte{OpPush1, 4, OpJump, OpPush1, OpJumpDest}).Execute(CallContext{GasLimit: 100}); err == nil {
+		t.Fatal("jump into push data allowed")
+	}
+}
+
+func TestExecutionFailures(t *testing.T) {
+	// Failed calls leave the storage as it was
+	contract := MakeContract([]byte{OpPush1, 1, OpPush1, 0, OpSStore, OpRevert})
+	if _, _, err := contract.Execute(CallContext{GasLimit: 1000}); err != ErrRevert {
+		t.Fatalf("err = %v, want ErrRevert", err)
+	}
+	if len(contract.Storage) != 0 {
+		t.Fatal("reverted write reached storage")
+	}
+
+	loop := MakeContract([]byte{OpJumpDest, OpPush1, 0, OpJump})
+	_, gasUsed, err := loop.Execute(CallContext{GasLimit: 100})
+	if err != ErrOutOfGas || gasUsed != 100 {
+		t.Fatalf("infinite loop: gas %d, err %v", gasUsed, err)
+	}
+
+	expensive := MakeContract([]byte{OpPush1, 1, OpPush1, 0, OpSStore})
+	if _, _, err := expensive.Execute(CallContext{GasLimit: GasSStore}); err != ErrOutOfGas {
+		t.Fatal("SSTORE ran without enough gas")
+	}
+
+	for name, code := range map[string][]byte{
+		"underflow":      {OpAdd},
+		"unknown opcode": {0xfe},
+		"truncated push": {OpPush32, 1, 2},
+	} {
+		if _, _, err := MakeContract(code).Execute(CallContext{GasLimit: 100}); err == nil {
+			t.Fatalf("%s: no error", name)
+		}
+	}
+
+	overflow := make([]byte, 0, 2*(MaxVMStackSize+1))
+	for i := 0; i <= MaxVMStackSize; i++ {
+		overflow = append(overflow, OpPush1, 1)
+	}
+	if _, _, err := MakeContract(overflow).Execute(CallContext{GasLimit: 10000}); err == nil {
+		t.Fatal("stack overflow not caught")
+	}
+}
@@ -5,6 +5,7 @@ go 1.13
 require (
 	9fans.net/go v0.0.2 // indirect
 	github.com/Matt146/Blockchain v0.0.0-20200620070929-f67dd01eca29
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.2.0
 	github.com/kisielk/errcheck v1.3.0 // indirect
 	github.com/libp2p/go-libp2p v0.6.0
 	github.com/libp2p/go-libp2p-core v0.5.0
@@ -15,6 +16,7 @@ require (
 	github.com/opentracing/opentracing-go v1.1.0
 	github.com/rogpeppe/godef v1.1.2 // indirect
 	github.com/sqs/goreturns v0.0.0-20181028201513-538ac6014518 // indirect
+	golang.org/x/crypto v0.0.0-20220214200702-86341886e292
 	golang.org/x/mod v0.3.0 // indirect
 	golang.org/x/tools v0.0.0-20200626171337-aa94e735be7f // indirect
 )
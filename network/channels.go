@@ -0,0 +1,38 @@
+package network
+
+import (
+	"Blockchain/blockchain"
+	"encoding/json"
+	"errors"
+)
+
+// SendChannelUpdate - Sends a signed payment channel state to the
+// recipient of the channel. Leave peerIP blank to go through the
+// flooding algorithm, the same way as with SendMSG
+func (net *Network) SendChannelUpdate(peerID []byte, peerIP string, state *blockchain.ChannelState) error {
+	msg, err := EncodeMessage(MsgChannelUpdate, state)
+	if err != nil {
+		return err
+	}
+	return net.SendMSG(peerID, peerIP, msg)
+}
+
+// DecodeChannelUpdate - Returns the payment channel state carried by a
+// packet from the message queue. Check it with Channel.StateIsValid
+// before relying on it
+func DecodeChannelUpdate(p Packet) (*blockchain.ChannelState, error) {
+	m, err := DecodeMessage(p.Data)
+	if err != nil {
+		return nil, err
+	}
+	if m.Kind != MsgChannelUpdate {
+		return nil, errors.New("DecodeChannelUpdate: packet doesn't carry a channel update")
+	}
+
+	var state blockchain.ChannelState
+	err = json.Unmarshal(m.Payload, &state)
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
@@ -5,9 +5,7 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"log"
-	"net/http"
 	"strconv"
-	"strings"
 )
 
 // Join - This function takes a boot node
@@ -31,23 +29,8 @@ func (net *Network) Join(addr string, listeningPort int) error {
 		SendType:      PacketSingleCast,
 	}
 
-	// Create the client and the request
-	client := &http.Client{}
-
-	// Craft form values for request. Ideally, we would use a packet
-	// and serializing that into form values but idgaf
-	formValues := p.SerializeToForm()
-
-	// Craft the request
-	req, err := http.NewRequest("POST", "http://"+addr+"/"+"JOIN", strings.NewReader(formValues.Encode()))
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Add("Content-Length", strconv.Itoa(len(formValues.Encode())))
-	if err != nil {
-		return err
-	}
-
 	// Send the request
-	resp, err := client.Do(req)
+	resp, err := net.transport().Send(addr, &p)
 	if err != nil {
 		return err
 	}
@@ -75,9 +58,9 @@ func (net *Network) Join(addr string, listeningPort int) error {
 	net.mux.Unlock()
 
 	// Now, print some debug information:
-	log.Println("[+] JOIN successful!")
+	debugLogln("[+] JOIN successful!")
 	for k := range net.Nodes {
-		log.Printf("\t- Node received: %v\n", base64.URLEncoding.EncodeToString([]byte(k)))
+		debugLogf("\t- Node received: %v\n", base64.URLEncoding.EncodeToString([]byte(k)))
 	}
 
 	return nil
@@ -96,13 +79,13 @@ func (net *Network) Ping(peerID []byte) error {
 		HopLimit:      HopLimitDefault,
 		SendType:      PacketSingleCast,
 	}
-	log.Printf("[+] Sending PING request to (%v, %v)\n", base64.URLEncoding.EncodeToString(p.DestinationID), net.Nodes[string(p.DestinationID)].IPAddr)
+	debugLogf("[+] Sending PING request to (%v, %v)\n", base64.URLEncoding.EncodeToString(p.DestinationID), net.Nodes[string(p.DestinationID)].IPAddr)
 	err := net.SendPacket(p)
 	if err != nil {
 		log.Printf("[+] Error occured during PING request: %s\n", err.Error())
 		return nil
 	}
-	log.Println("[+] PING successful!")
+	debugLogln("[+] PING successful!")
 	return err
 }
 
@@ -121,7 +104,7 @@ func (net *Network) Pong(peerID []byte, peerIP string) error {
 		HopLimit:      HopLimitDefault,
 		SendType:      PacketSingleCast,
 	}
-	log.Printf("[+] Sending PONG response (%v, %v)\n", base64.URLEncoding.EncodeToString(p.DestinationID), net.Nodes[string(p.DestinationID)].IPAddr)
+	debugLogf("[+] Sending PONG response (%v, %v)\n", base64.URLEncoding.EncodeToString(p.DestinationID), net.Nodes[string(p.DestinationID)].IPAddr)
 	if peerIP == "" {
 		err := net.SendPacket(p)
 		if err != nil {
@@ -156,7 +139,7 @@ func (net *Network) SendMSG(peerID []byte, peerIP string, msg []byte) error {
 		HopLimit:      HopLimitDefault,
 		SendType:      PacketSingleCast,
 	}
-	log.Printf("[+] Sending SendMSG request (%v, %v)\n", base64.URLEncoding.EncodeToString(p.DestinationID), net.Nodes[string(p.DestinationID)].IPAddr)
+	debugLogf("[+] Sending SendMSG request (%v, %v)\n", base64.URLEncoding.EncodeToString(p.DestinationID), net.Nodes[string(p.DestinationID)].IPAddr)
 	if peerIP == "" {
 		err := net.SendPacket(p)
 		if err != nil {
@@ -208,7 +191,7 @@ func (net *Network) BroadcastMSGResponse(peerID []byte, peerIP string, msg []byt
 		HopLimit:      HopLimitDefault,
 		SendType:      PacketSingleCast,
 	}
-	log.Printf("[+] Sending BroadcastMSGResponse response (%v, %v)\n", base64.URLEncoding.EncodeToString(p.DestinationID), net.Nodes[string(p.DestinationID)].IPAddr)
+	debugLogf("[+] Sending BroadcastMSGResponse response (%v, %v)\n", base64.URLEncoding.EncodeToString(p.DestinationID), net.Nodes[string(p.DestinationID)].IPAddr)
 	if peerIP == "" {
 		err := net.SendPacket(p)
 		if err != nil {
@@ -0,0 +1,76 @@
+package network
+
+import (
+	"Blockchain/blockchain"
+	"encoding/json"
+	"errors"
+)
+
+// FiltersRequest - Asks a full node for the block filters
+// starting at height From
+type FiltersRequest struct {
+	From uint64 `json:"From"`
+}
+
+// BlockRequest - Asks a full node for the block at a height
+type BlockRequest struct {
+	Height uint64 `json:"Height"`
+}
+
+// RequestFilters - Asks a full node for the block filters starting at
+// height from. The full node answers with a MsgFilters message
+func (net *Network) RequestFilters(peerID []byte, peerIP string, from uint64) error {
+	msg, err := EncodeMessage(MsgGetFilters, FiltersRequest{From: from})
+	if err != nil {
+		return err
+	}
+	return net.SendMSG(peerID, peerIP, msg)
+}
+
+// RequestBlock - Asks a full node for the block at a height, after its
+// filter matched. The full node answers with a MsgBlock message
+func (net *Network) RequestBlock(peerID []byte, peerIP string, height uint64) error {
+	msg, err := EncodeMessage(MsgGetBlock, BlockRequest{Height: height})
+	if err != nil {
+		return err
+	}
+	return net.SendMSG(peerID, peerIP, msg)
+}
+
+// DecodeFilters - Returns the block filters carried by a packet from
+// the message queue. Check them with HeaderChain.CheckFilter
+func DecodeFilters(p Packet) ([]blockchain.BlockFilter, error) {
+	m, err := DecodeMessage(p.Data)
+	if err != nil {
+		return nil, err
+	}
+	if m.Kind != MsgFilters {
+		return nil, errors.New("DecodeFilters: packet doesn't carry filters")
+	}
+
+	var filters []blockchain.BlockFilter
+	err = json.Unmarshal(m.Payload, &filters)
+	if err != nil {
+		return nil, err
+	}
+	return filters, nil
+}
+
+// DecodeBlock - Returns the block carried by a packet from the
+// message queue. Check it with HeaderChain.CheckBlock
+func DecodeBlock(p Packet) (*blockchain.Block, error) {
+	m, err := DecodeMessage(p.Data)
+	if err != nil {
+		return nil, err
+	}
+	if m.Kind != MsgBlock {
+		return nil, errors.New("DecodeBlock: packet doesn't carry a block")
+	}
+
+	var b blockchain.Block
+	err = json.Unmarshal(m.Payload, &b)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
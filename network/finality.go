@@ -0,0 +1,36 @@
+package network
+
+import (
+	"Blockchain/blockchain"
+	"encoding/json"
+)
+
+// BroadcastVote - Broadcasts a finality vote to all peers
+func (net *Network) BroadcastVote(v *blockchain.Vote) error {
+	msg, err := EncodeMessage(MsgVote, v)
+	if err != nil {
+		return err
+	}
+	return net.BroadcastMSG(msg)
+}
+
+// HandleVotePacket - Hands the vote carried by a packet from the
+// message queue to the finality gadget. Returns true if the packet
+// carried a vote and that vote made a new block final. Packets that
+// don't carry a vote are ignored
+func HandleVotePacket(g *blockchain.FinalityGadget, p Packet) (bool, error) {
+	m, err := DecodeMessage(p.Data)
+	if err != nil {
+		return false, err
+	}
+	if m.Kind != MsgVote {
+		return false, nil
+	}
+
+	var v blockchain.Vote
+	err = json.Unmarshal(m.Payload, &v)
+	if err != nil {
+		return false, err
+	}
+	return g.AddVote(v)
+}
@@ -0,0 +1,71 @@
+package network
+
+import (
+	"encoding/json"
+)
+
+const (
+	// MsgVote - A finality vote (blockchain.Vote)
+	MsgVote = "Vote"
+
+	// MsgChannelUpdate - A signed payment channel state
+	// (blockchain.ChannelState)
+	MsgChannelUpdate = "ChannelUpdate"
+
+	// MsgGetHeaders - A light client asking for block
+	// headers (HeadersRequest)
+	MsgGetHeaders = "GetHeaders"
+
+	// MsgHeaders - Block headers ([]blockchain.BlockHeader)
+	MsgHeaders = "Headers"
+
+	// MsgGetMerkleProof - A light client asking for the Merkle
+	// proof of a transaction (the transaction hash)
+	MsgGetMerkleProof = "GetMerkleProof"
+
+	// MsgMerkleProof - The Merkle proof of a transaction
+	// (blockchain.MerkleProof)
+	MsgMerkleProof = "MerkleProof"
+
+	// MsgGetFilters - A light client asking for block
+	// filters (FiltersRequest)
+	MsgGetFilters = "GetFilters"
+
+	// MsgFilters - Block filters with their filter
+	// headers ([]blockchain.BlockFilter)
+	MsgFilters = "Filters"
+
+	// MsgGetBlock - A light client asking for the block
+	// at a height (BlockRequest)
+	MsgGetBlock = "GetBlock"
+
+	// MsgBlock - A whole block (blockchain.Block)
+	MsgBlock = "Block"
+)
+
+// Message - The envelope application data gets wrapped in before it
+// is put into the Data of a SendMSG or BroadcastMSG packet, so that
+// the receiver knows how to decode the payload
+type Message struct {
+	Kind    string `json:"Kind"`
+	Payload []byte `json:"Payload"`
+}
+
+// EncodeMessage - Serializes v and wraps it in a message of the given kind
+func EncodeMessage(kind string, v interface{}) ([]byte, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Message{Kind: kind, Payload: payload})
+}
+
+// DecodeMessage - Unwraps the message carried in the Data of a packet
+func DecodeMessage(data []byte) (*Message, error) {
+	var m Message
+	err := json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -4,33 +4,85 @@ import (
 	"sync"
 )
 
-var mux sync.Mutex
+// MsgQueue - Map between the SourceID of the packet to a slice
+// of msg packets from that SourceID. A Network with no MsgQueue
+// of its own uses the package-wide one
+type MsgQueue struct {
+	packets map[string][]Packet
+	mux     sync.Mutex
+}
+
+// msgQueue - The package-wide message queue
+var msgQueue MsgQueue
+
+// MakeMsgQueue - MsgQueue constructor
+func MakeMsgQueue() *MsgQueue {
+	return &MsgQueue{packets: make(map[string][]Packet)}
+}
+
+// Add - Adds a msg packet to the queue in a thread-safe way
+func (q *MsgQueue) Add(p Packet) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	q.packets[string(p.SourceID)] = append(q.packets[string(p.SourceID)], p)
+}
+
+// Handle - returns the queued packets from a peer, without copying
+// them, and deletes any reference to them in the queue
+func (q *MsgQueue) Handle(peerID []byte) []Packet {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	packets := q.packets[string(peerID)]
+	delete(q.packets, string(peerID))
+	return packets
+}
 
-// msgQueue - Map between the SourceID of the packet to a slice
-// of msg packets from that SourceID
-var msgQueue map[string][]Packet
+// Drain - returns the queued packets of every peer and empties
+// the queue
+func (q *MsgQueue) Drain() []Packet {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	var packets []Packet
+	for peerID := range q.packets {
+		packets = append(packets, q.packets[peerID]...)
+		delete(q.packets, peerID)
+	}
+	return packets
+}
 
 // InitMSGQueue - Initializes the msgQueue. This needs to be called before using
 // the message queue
 func InitMSGQueue() {
-	msgQueue = make(map[string][]Packet)
+	msgQueue.mux.Lock()
+	defer msgQueue.mux.Unlock()
+	msgQueue.packets = make(map[string][]Packet)
 }
 
 // AddToMsgQueue - Adds a msg packet to the msgQueue
 // in a thread-safe way
 func (p *Packet) AddToMsgQueue() {
-	mux.Lock()
-	defer mux.Unlock()
-	msgQueue[string(p.SourceID)] = append(msgQueue[string(p.SourceID)], *p)
+	msgQueue.Add(*p)
 }
 
 // HandleMsgQueuePackets - returns the message queue packets,
 // without copying them, and deletes any reference to them in the
 // internal msgQueue map
 func HandleMsgQueuePackets(peerID []byte) []Packet {
-	mux.Lock()
-	defer mux.Unlock()
-	packets := msgQueue[string(peerID)]
-	delete(msgQueue, string(peerID))
-	return packets
+	return msgQueue.Handle(peerID)
+}
+
+// DrainMsgQueue - returns the message queue packets of every
+// peer and empties the internal msgQueue map
+func DrainMsgQueue() []Packet {
+	return msgQueue.Drain()
+}
+
+// queueMsg - Adds a msg packet for me to my own MsgQueue, or
+// to the package-wide one if I don't have one
+func (net *Network) queueMsg(p *Packet) {
+	if net.MsgQueue != nil {
+		net.MsgQueue.Add(*p)
+		return
+	}
+	p.AddToMsgQueue()
 }
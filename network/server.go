@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"strings"
 
@@ -40,16 +39,16 @@ func (net *Network) RouteIfNeeded(w http.ResponseWriter, r *http.Request) (int,
 	}
 
 	// Print debug information about packet
-	fmt.Println("Packet DEBUG information:")
-	fmt.Println("=========================")
-	fmt.Printf("Source ID: %v\n", p.SourceID)
-	fmt.Printf("Destination ID: %v\n", p.DestinationID)
-	fmt.Printf("My ID: %v\n", net.MyID)
-	fmt.Printf("Source IP: %v\n", p.SourceIP)
-	fmt.Printf("Destination IP: %v\n", p.DestinationIP)
-	fmt.Printf("My IP: %v\n", p.DestinationIP)
-	fmt.Printf("Type: %v\n", p.Type)
-	fmt.Println("=========================")
+	debugln("Packet DEBUG information:")
+	debugln("=========================")
+	debugf("Source ID: %v\n", p.SourceID)
+	debugf("Destination ID: %v\n", p.DestinationID)
+	debugf("My ID: %v\n", net.MyID)
+	debugf("Source IP: %v\n", p.SourceIP)
+	debugf("Destination IP: %v\n", p.DestinationIP)
+	debugf("My IP: %v\n", p.DestinationIP)
+	debugf("Type: %v\n", p.Type)
+	debugln("=========================")
 
 	/*
 		// Now check destination IP and ID. If they match my destination ID
@@ -67,7 +66,7 @@ func (net *Network) RouteIfNeeded(w http.ResponseWriter, r *http.Request) (int,
 	// the packet is meant for me. Otherwise, keep going in the function
 	// to route the packet
 	if bytes.Compare(p.DestinationID, net.MyID) == 0 {
-		fmt.Println("Packet is for me: :D")
+		debugln("Packet is for me: :D")
 		return 1, nil
 	}
 
@@ -75,11 +74,11 @@ func (net *Network) RouteIfNeeded(w http.ResponseWriter, r *http.Request) (int,
 	// is.
 	if bytes.Compare(p.SourceID, net.MyID) == 0 {
 		if strings.Compare(r.FormValue("SourceIP"), net.MyIP) == 0 {
-			fmt.Printf("My own packet was sent back to me :(\n")
-			fmt.Printf("ID: Source: %v | Destination: %v\n", p.SourceID, p.DestinationID)
-			fmt.Printf("IP: Source: %v | Destination: %v\n", p.SourceIP, p.DestinationIP)
-			fmt.Printf("My ID: %v\n", net.MyID)
-			fmt.Printf("My IP: %v\n", net.MyIP)
+			debugf("My own packet was sent back to me :(\n")
+			debugf("ID: Source: %v | Destination: %v\n", p.SourceID, p.DestinationID)
+			debugf("IP: Source: %v | Destination: %v\n", p.SourceIP, p.DestinationIP)
+			debugf("My ID: %v\n", net.MyID)
+			debugf("My IP: %v\n", net.MyIP)
 			return 2, nil
 		}
 	}
@@ -149,7 +148,7 @@ func (net *Network) JoinHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Printf("Got JOIN request from: %v\n", r.RemoteAddr)
+	debugf("Got JOIN request from: %v\n", r.RemoteAddr)
 	/*
 		fmt.Println("\tJOIN RESPONSE JSON:")
 		fmt.Printf("===============================\n")
@@ -199,7 +198,7 @@ func (net *Network) LeaveHandler(w http.ResponseWriter, r *http.Request) {
 
 // PingHandler - Handle a ping packet
 func (net *Network) PingHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("Received ping!")
+	debugln("Received ping!")
 	result, err := net.RouteIfNeeded(w, r)
 	if err != nil {
 		fmt.Println("ERROR HAPPENED")
@@ -208,9 +207,9 @@ func (net *Network) PingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Printf("Packet router output: %d\n", result)
+	debugf("Packet router output: %d\n", result)
 	if result == 1 {
-		fmt.Println("PROCESSING PING")
+		debugln("PROCESSING PING")
 		// @TODO CHANGE: Respond with just a simple ACK
 		w.Write([]byte("ACK"))
 
@@ -229,7 +228,7 @@ func (net *Network) PingHandler(w http.ResponseWriter, r *http.Request) {
 
 // PongHandler - Handle a pong packet
 func (net *Network) PongHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("PONG :D")
+	debugln("PONG :D")
 	result, err := net.RouteIfNeeded(w, r)
 	if err != nil {
 		fmt.Println(result)
@@ -238,7 +237,7 @@ func (net *Network) PongHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if result == 1 {
-		fmt.Println("HANDLING PONG! YAY!")
+		debugln("HANDLING PONG! YAY!")
 		// @TODO CHANGE: Respond with just a simple ACK
 		w.Write([]byte("ACK"))
 
@@ -264,7 +263,7 @@ func (net *Network) PongHandler(w http.ResponseWriter, r *http.Request) {
 
 // SendMSGHandler - THe handler function for a SendMSG request
 func (net *Network) SendMSGHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("[+] Received a SendMSG")
+	debugLogln("[+] Received a SendMSG")
 	result, err := net.RouteIfNeeded(w, r)
 	if err != nil {
 		fmt.Println(result)
@@ -273,19 +272,19 @@ func (net *Network) SendMSGHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if result == 1 {
-		log.Println("[+] Stuffing it into the MsgQueue")
+		debugLogln("[+] Stuffing it into the MsgQueue")
 		packet, err := DeserializeFromForm(r)
 		if err != nil {
 			elog.Error(err)
 			return
 		}
-		packet.AddToMsgQueue()
+		net.queueMsg(packet)
 	}
 }
 
 // BroadcastMSGHandler - THe handler function for a BroadcastMSG request
 func (net *Network) BroadcastMSGHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("[+] Received a BroadcastMSG")
+	debugLogln("[+] Received a BroadcastMSG")
 	result, err := net.RouteIfNeeded(w, r)
 	if err != nil {
 		fmt.Println(result)
@@ -294,20 +293,20 @@ func (net *Network) BroadcastMSGHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	if result == 1 {
-		log.Println("[+] Stuffing it into the MsgQueue")
+		debugLogln("[+] Stuffing it into the MsgQueue")
 		packet, err := DeserializeFromForm(r)
 		if err != nil {
 			elog.Error(err)
 			return
 		}
-		packet.AddToMsgQueue()
+		net.queueMsg(packet)
 	}
 }
 
 // BroadcastMSGResponseHandler - The handler function  for
 // a BroadcastMSGResponse.
 func (net *Network) BroadcastMSGResponseHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("[+] Received a BroadcastMSGResponse")
+	debugLogln("[+] Received a BroadcastMSGResponse")
 	result, err := net.RouteIfNeeded(w, r)
 	if err != nil {
 		fmt.Println(result)
@@ -316,12 +315,26 @@ func (net *Network) BroadcastMSGResponseHandler(w http.ResponseWriter, r *http.R
 	}
 
 	if result == 1 {
-		log.Println("[+] Stuffing it into the MsgQueue")
+		debugLogln("[+] Stuffing it into the MsgQueue")
 		packet, err := DeserializeFromForm(r)
 		if err != nil {
 			elog.Error(err)
 			return
 		}
-		packet.AddToMsgQueue()
+		net.queueMsg(packet)
 	}
 }
+
+// Handler - Returns an http.Handler that routes every packet Type
+// to its handler, for serving the network over any listener
+func (net *Network) Handler() http.Handler {
+	m := http.NewServeMux()
+	m.HandleFunc("/JOIN", net.JoinHandler)
+	m.HandleFunc("/LEAVE", net.LeaveHandler)
+	m.HandleFunc("/PING", net.PingHandler)
+	m.HandleFunc("/PONG", net.PongHandler)
+	m.HandleFunc("/SendMSG", net.SendMSGHandler)
+	m.HandleFunc("/BroadcastMSG", net.BroadcastMSGHandler)
+	m.HandleFunc("/BroadcastMSGResponse", net.BroadcastMSGResponseHandler)
+	return m
+}
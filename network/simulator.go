@@ -0,0 +1,318 @@
+package network
+
+import (
+	"Blockchain/blockchain"
+	"container/heap"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SimPort - The port every simulated node listens on
+	SimPort = 8080
+
+	// DefaultSimLatency - How long a packet takes to cross a link
+	// in the simulator, in virtual time
+	DefaultSimLatency = 50 * time.Millisecond
+)
+
+// simEvent - A packet in flight between two simulated nodes
+type simEvent struct {
+	at    time.Duration
+	seq   uint64
+	from  string
+	to    string
+	pType string
+	body  string
+}
+
+// simEventQueue - A heap of packets in flight, ordered by arrival
+// time. Packets arriving at the same time get delivered in order of
+// destination and then of sending, so runs don't depend on the map
+// order that SendPacket and BroadcastPacket loop through
+type simEventQueue []*simEvent
+
+func (q simEventQueue) Len() int { return len(q) }
+
+func (q simEventQueue) Less(i, j int) bool {
+	if q[i].at != q[j].at {
+		return q[i].at < q[j].at
+	}
+	if q[i].to != q[j].to {
+		return q[i].to < q[j].to
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q simEventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *simEventQueue) Push(x interface{}) { *q = append(*q, x.(*simEvent)) }
+
+func (q *simEventQueue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+// Simulator - Runs N Networks in one process, connected through an
+// in-memory transport, on a virtual clock. Nothing happens until
+// Step, RunFor or RunUntilIdle delivers the packets in flight, so
+// propagation, joins and partitions play out the same way every run.
+// Node 0 is bootstrapped; the rest have to Join
+type Simulator struct {
+	Nodes     []*Network
+	Delivered int // packets handed to a handler
+	Dropped   int // packets lost to partitions or unknown addresses
+
+	now      time.Duration
+	latency  time.Duration
+	links    map[string]time.Duration
+	addrs    []string
+	handlers map[string]http.Handler
+	groups   map[string]int
+	events   simEventQueue
+	seq      uint64
+}
+
+// memTransport - The Transport of a simulated node. Packets go
+// through the simulator instead of over HTTP
+type memTransport struct {
+	sim  *Simulator
+	addr string
+}
+
+// Send - Hands a packet to the simulator
+func (t *memTransport) Send(addr string, p *Packet) (*http.Response, error) {
+	return t.sim.send(t.addr, addr, p)
+}
+
+// MakeSimulator - Simulator constructor. Makes n nodes, each with
+// its own MsgQueue, listening on nodeI:SimPort
+func MakeSimulator(n int) *Simulator {
+	s := &Simulator{
+		latency:  DefaultSimLatency,
+		links:    make(map[string]time.Duration),
+		handlers: make(map[string]http.Handler),
+		groups:   make(map[string]int),
+	}
+	for i := 0; i < n; i++ {
+		addr := "node" + strconv.Itoa(i) + ":" + strconv.Itoa(SimPort)
+		net := MakeNetwork()
+		net.Transport = &memTransport{sim: s, addr: addr}
+		net.MsgQueue = MakeMsgQueue()
+		s.Nodes = append(s.Nodes, net)
+		s.addrs = append(s.addrs, addr)
+		s.handlers[addr] = net.Handler()
+	}
+	if n > 0 {
+		s.Nodes[0].MyID = blockchain.GenRandBytes(32)
+		s.Nodes[0].MyIP = s.addrs[0]
+	}
+	return s
+}
+
+/************************************
+ * Topology
+************************************/
+
+// Addr - Returns the address (IP:port) of a node
+func (s *Simulator) Addr(i int) string {
+	return s.addrs[i]
+}
+
+// Join - Makes node i join the network through node via. JOIN is
+// a request/response, so it happens right away rather than on the
+// clock, and fails if the two nodes are partitioned
+func (s *Simulator) Join(i int, via int) error {
+	return s.Nodes[i].Join(s.addrs[via], SimPort)
+}
+
+// JoinAll - Makes every node but node 0 join through node 0
+func (s *Simulator) JoinAll() error {
+	for i := 1; i < len(s.Nodes); i++ {
+		err := s.Join(i, 0)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetLatency - Sets the latency of every link without one of
+// its own
+func (s *Simulator) SetLatency(d time.Duration) {
+	s.latency = d
+}
+
+// SetLinkLatency - Sets the latency between two nodes, both ways
+func (s *Simulator) SetLinkLatency(i int, j int, d time.Duration) {
+	s.links[s.addrs[i]+"|"+s.addrs[j]] = d
+	s.links[s.addrs[j]+"|"+s.addrs[i]] = d
+}
+
+// Partition - Splits the nodes into groups that can't reach each
+// other. Nodes in no group end up together in a group of their own.
+// Packets already in flight across the partition get dropped
+func (s *Simulator) Partition(groups ...[]int) {
+	s.groups = make(map[string]int)
+	for g := range groups {
+		for _, i := range groups[g] {
+			s.groups[s.addrs[i]] = g + 1
+		}
+	}
+}
+
+// Heal - Removes every partition
+func (s *Simulator) Heal() {
+	s.groups = make(map[string]int)
+}
+
+// reachable - Returns true if a packet can get from one address
+// to the other
+func (s *Simulator) reachable(from string, to string) bool {
+	_, exists := s.handlers[to]
+	return exists && s.groups[from] == s.groups[to]
+}
+
+// linkLatency - Returns the latency from one address to the other
+func (s *Simulator) linkLatency(from string, to string) time.Duration {
+	if d, exists := s.links[from+"|"+to]; exists {
+		return d
+	}
+	return s.latency
+}
+
+/************************************
+ * Clock
+************************************/
+
+// Now - Returns the virtual time since the simulator started
+func (s *Simulator) Now() time.Duration {
+	return s.now
+}
+
+// Pending - Returns the number of packets in flight
+func (s *Simulator) Pending() int {
+	return len(s.events)
+}
+
+// Step - Moves the clock to the next packet in flight and delivers
+// it. Returns false if there was nothing to deliver
+func (s *Simulator) Step() bool {
+	if len(s.events) == 0 {
+		return false
+	}
+	e := heap.Pop(&s.events).(*simEvent)
+	s.now = e.at
+
+	if !s.reachable(e.from, e.to) {
+		s.Dropped++
+		return true
+	}
+	s.serve(e.from, e.to, e.pType, e.body)
+	s.Delivered++
+	return true
+}
+
+// RunFor - Delivers everything that arrives in the next d of
+// virtual time, then moves the clock to the end of it
+func (s *Simulator) RunFor(d time.Duration) {
+	end := s.now + d
+	for len(s.events) > 0 && s.events[0].at <= end {
+		s.Step()
+	}
+	s.now = end
+}
+
+// RunUntilIdle - Delivers packets until none are left in flight.
+// Returns how many it went through
+func (s *Simulator) RunUntilIdle() int {
+	steps := 0
+	for s.Step() {
+		steps++
+	}
+	return steps
+}
+
+/************************************
+ * Transport
+************************************/
+
+// send - Puts a packet from one address to another on the clock.
+// The sender gets an empty 202 right away, the way it would if the
+// other end queued the packet. Packets to unknown addresses just
+// get lost, since SendPacket gives up on the first error and which
+// nodes it reached would depend on map order
+func (s *Simulator) send(from string, to string, p *Packet) (*http.Response, error) {
+	form := p.SerializeToForm()
+	body := form.Encode()
+
+	if p.Type == "JOIN" {
+		if !s.reachable(from, to) {
+			return nil, errors.New("send: can't reach " + to)
+		}
+		return s.serve(from, to, p.Type, body), nil
+	}
+
+	s.seq++
+	heap.Push(&s.events, &simEvent{
+		at:    s.now + s.linkLatency(from, to),
+		seq:   s.seq,
+		from:  from,
+		to:    to,
+		pType: p.Type,
+		body:  body,
+	})
+
+	rec := httptest.NewRecorder()
+	rec.WriteHeader(http.StatusAccepted)
+	return rec.Result(), nil
+}
+
+// serve - Runs the handler of the node at an address on a packet
+// and returns its response
+func (s *Simulator) serve(from string, to string, pType string, body string) *http.Response {
+	req := httptest.NewRequest("POST", "/"+pType, strings.NewReader(body))
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = from
+	rec := httptest.NewRecorder()
+	s.handlers[to].ServeHTTP(rec, req)
+	return rec.Result()
+}
+
+/************************************
+ * Inspection
+************************************/
+
+// Messages - Empties the MsgQueue of node i. The packets are
+// grouped by sender, in the order they came in
+func (s *Simulator) Messages(i int) []Packet {
+	packets := s.Nodes[i].MsgQueue.Drain()
+	sort.SliceStable(packets, func(a, b int) bool {
+		return string(packets[a].SourceID) < string(packets[b].SourceID)
+	})
+	return packets
+}
+
+// Knows - Returns true if node i has node j in its routing table
+func (s *Simulator) Knows(i int, j int) bool {
+	for _, node := range s.Nodes[i].Nodes {
+		if node.IPAddr == s.addrs[j] {
+			return true
+		}
+	}
+	return false
+}
+
+// String - Returns a one line summary of the simulator
+func (s *Simulator) String() string {
+	return fmt.Sprintf("t=%v nodes=%d pending=%d delivered=%d dropped=%d",
+		s.now, len(s.Nodes), len(s.events), s.Delivered, s.Dropped)
+}
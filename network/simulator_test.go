@@ -0,0 +1,103 @@
+package network
+
+import (
+	"testing"
+	"time"
+)
+
+// broadcastRun - Joins n simulated nodes, has node 0 broadcast a
+// message and returns the simulator once the network is idle, along
+// with how many copies of the message every node got
+func broadcastRun(t *testing.T, n int) (*Simulator, []int) {
+	t.Helper()
+	sim := MakeSimulator(n)
+	if err := sim.JoinAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.Nodes[0].BroadcastMSG([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	sim.RunUntilIdle()
+
+	var received []int
+	for i := range sim.Nodes {
+		received = append(received, len(sim.Messages(i)))
+	}
+	return sim, received
+}
+
+func TestSimulatorIsDeterministic(t *testing.T) {
+	first, firstReceived := broadcastRun(t, 8)
+	for run := 0; run < 5; run++ {
+		sim, received := broadcastRun(t, 8)
+		if sim.String() != first.String() {
+			t.Fatalf("run %d: %s, first run: %s", run, sim, first)
+		}
+		for i := range received {
+			if received[i] != firstReceived[i] {
+				t.Fatalf("run %d: node %d got %d messages, %d the first time", run, i, received[i], firstReceived[i])
+			}
+		}
+	}
+	for i := 1; i < len(firstReceived); i++ {
+		if firstReceived[i] == 0 {
+			t.Fatalf("node %d never got the broadcast", i)
+		}
+	}
+}
+
+func TestSimulatorClock(t *testing.T) {
+	sim := MakeSimulator(3)
+	if err := sim.JoinAll(); err != nil {
+		t.Fatal(err)
+	}
+	sim.SetLatency(10 * time.Millisecond)
+	sim.SetLinkLatency(0, 2, 100*time.Millisecond)
+	if err := sim.Nodes[0].BroadcastMSG([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing arrives before the latency of its link
+	sim.RunFor(5 * time.Millisecond)
+	if sim.Delivered != 0 || sim.Now() != 5*time.Millisecond {
+		t.Fatalf("after 5ms: %s", sim)
+	}
+	sim.RunFor(10 * time.Millisecond)
+	if len(sim.Messages(1)) == 0 || len(sim.Messages(2)) != 0 {
+		t.Fatal("the fast link didn't deliver first")
+	}
+	sim.RunUntilIdle()
+	if sim.Pending() != 0 || sim.Now() < 100*time.Millisecond {
+		t.Fatalf("after running until idle: %s", sim)
+	}
+}
+
+func TestSimulatorPartition(t *testing.T) {
+	sim := MakeSimulator(4)
+	if err := sim.JoinAll(); err != nil {
+		t.Fatal(err)
+	}
+	sim.Partition([]int{0, 1}, []int{2, 3})
+	if sim.Join(2, 0) == nil {
+		t.Fatal("joined across a partition")
+	}
+	if err := sim.Nodes[0].BroadcastMSG([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	sim.RunUntilIdle()
+	if len(sim.Messages(1)) == 0 {
+		t.Fatal("message didn't reach its own side of the partition")
+	}
+	if len(sim.Messages(2)) != 0 || len(sim.Messages(3)) != 0 || sim.Dropped == 0 {
+		t.Fatal("message crossed the partition")
+	}
+
+	sim.Heal()
+	if err := sim.Nodes[0].BroadcastMSG([]byte("again")); err != nil {
+		t.Fatal(err)
+	}
+	sim.RunUntilIdle()
+	if len(sim.Messages(2)) == 0 || len(sim.Messages(3)) == 0 {
+		t.Fatal("message didn't reach the other side after healing")
+	}
+}
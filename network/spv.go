@@ -0,0 +1,134 @@
+package network
+
+import (
+	"Blockchain/blockchain"
+	"encoding/json"
+	"errors"
+)
+
+// HeadersRequest - Asks a full node for the block headers
+// starting at height From
+type HeadersRequest struct {
+	From uint64 `json:"From"`
+}
+
+// RequestHeaders - Asks a full node for the block headers starting at
+// height from. The full node answers with a MsgHeaders message.
+// Leave peerIP blank to go through the flooding algorithm, the same
+// way as with SendMSG
+func (net *Network) RequestHeaders(peerID []byte, peerIP string, from uint64) error {
+	msg, err := EncodeMessage(MsgGetHeaders, HeadersRequest{From: from})
+	if err != nil {
+		return err
+	}
+	return net.SendMSG(peerID, peerIP, msg)
+}
+
+// RequestMerkleProof - Asks a full node for the Merkle proof of a
+// transaction. The full node answers with a MsgMerkleProof message
+func (net *Network) RequestMerkleProof(peerID []byte, peerIP string, txHash []byte) error {
+	msg, err := EncodeMessage(MsgGetMerkleProof, txHash)
+	if err != nil {
+		return err
+	}
+	return net.SendMSG(peerID, peerIP, msg)
+}
+
+// HandleSPVRequest - Answers a header, Merkle proof, filter or block
+// request carried by a packet from the message queue, using the blockchain of this
+// full node. Returns true if the packet carried such a request.
+// Other packets are ignored
+func (net *Network) HandleSPVRequest(bc *blockchain.Blockchain, p Packet) (bool, error) {
+	m, err := DecodeMessage(p.Data)
+	if err != nil {
+		return false, err
+	}
+
+	var msg []byte
+	switch m.Kind {
+	case MsgGetHeaders:
+		var req HeadersRequest
+		err = json.Unmarshal(m.Payload, &req)
+		if err != nil {
+			return true, err
+		}
+		snapshot := bc.Snapshot()
+		msg, err = EncodeMessage(MsgHeaders, snapshot.Headers(req.From, blockchain.MaxHeadersPerMessage))
+	case MsgGetMerkleProof:
+		var txHash []byte
+		err = json.Unmarshal(m.Payload, &txHash)
+		if err != nil {
+			return true, err
+		}
+		snapshot := bc.Snapshot()
+		var proof *blockchain.MerkleProof
+		proof, err = snapshot.FindMerkleProof(txHash)
+		if err != nil {
+			return true, err
+		}
+		msg, err = EncodeMessage(MsgMerkleProof, proof)
+	case MsgGetFilters:
+		var req FiltersRequest
+		err = json.Unmarshal(m.Payload, &req)
+		if err != nil {
+			return true, err
+		}
+		snapshot := bc.Snapshot()
+		msg, err = EncodeMessage(MsgFilters, snapshot.BlockFilters(req.From, blockchain.MaxFiltersPerMessage))
+	case MsgGetBlock:
+		var req BlockRequest
+		err = json.Unmarshal(m.Payload, &req)
+		if err != nil {
+			return true, err
+		}
+		snapshot := bc.Snapshot()
+		if req.Height >= uint64(len(snapshot)) {
+			return true, errors.New("HandleSPVRequest: no block at that height")
+		}
+		msg, err = EncodeMessage(MsgBlock, snapshot[req.Height])
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	return true, net.SendMSG(p.SourceID, p.SourceIP, msg)
+}
+
+// DecodeHeaders - Returns the block headers carried by a packet from
+// the message queue. Add them to a HeaderChain to verify them
+func DecodeHeaders(p Packet) ([]blockchain.BlockHeader, error) {
+	m, err := DecodeMessage(p.Data)
+	if err != nil {
+		return nil, err
+	}
+	if m.Kind != MsgHeaders {
+		return nil, errors.New("DecodeHeaders: packet doesn't carry headers")
+	}
+
+	var headers []blockchain.BlockHeader
+	err = json.Unmarshal(m.Payload, &headers)
+	if err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
+// DecodeMerkleProof - Returns the Merkle proof carried by a packet from
+// the message queue. Check it with HeaderChain.VerifyInclusion
+func DecodeMerkleProof(p Packet) (*blockchain.MerkleProof, error) {
+	m, err := DecodeMessage(p.Data)
+	if err != nil {
+		return nil, err
+	}
+	if m.Kind != MsgMerkleProof {
+		return nil, errors.New("DecodeMerkleProof: packet doesn't carry a Merkle proof")
+	}
+
+	var proof blockchain.MerkleProof
+	err = json.Unmarshal(m.Payload, &proof)
+	if err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
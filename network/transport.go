@@ -0,0 +1,48 @@
+package network
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Transport - Gets a packet to the handler for its Type on the node
+// at addr (IP:port) and returns the response of that handler. The
+// handlers on the other end are the ones in server.go
+type Transport interface {
+	Send(addr string, p *Packet) (*http.Response, error)
+}
+
+// HTTPTransport - The default Transport. POSTs the packet as an
+// HTTP form to http://addr/Type
+type HTTPTransport struct{}
+
+// Send - Sends a packet over HTTP
+func (HTTPTransport) Send(addr string, p *Packet) (*http.Response, error) {
+	// Create the client
+	client := &http.Client{}
+
+	// Craft form values for request
+	formValues := p.SerializeToForm()
+
+	// Craft the request
+	req, err := http.NewRequest("POST", "http://"+addr+"/"+p.Type, strings.NewReader(formValues.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("Content-Length", strconv.Itoa(len(formValues.Encode())))
+	debugf("URL: %s://%s%s\n", req.URL.Scheme, req.URL.Host, req.URL.Path)
+
+	// Send the request
+	return client.Do(req)
+}
+
+// transport - Returns the Transport of the network, which is
+// HTTP unless another one was set
+func (net *Network) transport() Transport {
+	if net.Transport == nil {
+		return HTTPTransport{}
+	}
+	return net.Transport
+}
@@ -5,11 +5,12 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/url"
 	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -33,6 +34,54 @@ const (
 	PacketBroadCast = 1
 )
 
+// verbose - Whether debug output about every packet gets printed.
+// It's 1 if it does. Nodes read it from their own goroutines, so it
+// only gets touched atomically
+var verbose int32 = 0
+
+// SetVerbose - Turns the debug output about every packet a node
+// sends, routes and handles on or off. It's off by default
+func SetVerbose(v bool) {
+	var flag int32 = 0
+	if v {
+		flag = 1
+	}
+	atomic.StoreInt32(&verbose, flag)
+}
+
+// Verbose - Returns true if the debug output is on
+func Verbose() bool {
+	return atomic.LoadInt32(&verbose) == 1
+}
+
+// debugf - Prints debug output if it's on
+func debugf(format string, a ...interface{}) {
+	if Verbose() {
+		fmt.Printf(format, a...)
+	}
+}
+
+// debugln - Prints a line of debug output if it's on
+func debugln(a ...interface{}) {
+	if Verbose() {
+		fmt.Println(a...)
+	}
+}
+
+// debugLogf - Logs debug output if it's on
+func debugLogf(format string, a ...interface{}) {
+	if Verbose() {
+		log.Printf(format, a...)
+	}
+}
+
+// debugLogln - Logs a line of debug output if it's on
+func debugLogln(a ...interface{}) {
+	if Verbose() {
+		log.Println(a...)
+	}
+}
+
 // Node - Models a singular node in the network
 type Node struct {
 	ID       []byte `json:"ID"`       // Unique identifier for each node
@@ -43,10 +92,12 @@ type Node struct {
 
 // Network - represents the network
 type Network struct {
-	MyID  []byte          `json:"MyID"`
-	MyIP  string          `json:"MyIP"`
-	Nodes map[string]Node `json:"Nodes"`
-	mux   sync.Mutex
+	MyID      []byte          `json:"MyID"`
+	MyIP      string          `json:"MyIP"`
+	Nodes     map[string]Node `json:"Nodes"`
+	Transport Transport       `json:"-"` // HTTPTransport if nil
+	MsgQueue  *MsgQueue       `json:"-"` // the package-wide queue if nil
+	mux       sync.Mutex
 }
 
 // Packet - Models a packet for the P2P protocol.
@@ -119,10 +170,10 @@ func (net *Network) BootstrapNetwork() {
 
 // SerializeToForm - Serialized a packet to an HTTP form
 func (p *Packet) SerializeToForm() url.Values {
-	fmt.Printf("Protocol version given to packet serializer: %d\n", p.PVersion)
-	fmt.Printf("Serialized version given to packet serializer: %s\n", strconv.FormatInt(int64(p.PVersion), blockchain.Base))
-	fmt.Printf("Destination ID given to packet serialzier: %v\n", p.DestinationID)
-	fmt.Printf("Serialzied destination ID given to packet serialzier: %s\n", base64.URLEncoding.EncodeToString(p.DestinationID))
+	debugf("Protocol version given to packet serializer: %d\n", p.PVersion)
+	debugf("Serialized version given to packet serializer: %s\n", strconv.FormatInt(int64(p.PVersion), blockchain.Base))
+	debugf("Destination ID given to packet serialzier: %v\n", p.DestinationID)
+	debugf("Serialzied destination ID given to packet serialzier: %s\n", base64.URLEncoding.EncodeToString(p.DestinationID))
 	formValues := url.Values{
 		"PVersion":      {strconv.FormatInt(int64(p.PVersion), blockchain.Base)},
 		"Type":          {p.Type},
@@ -196,37 +247,21 @@ func DeserializeFromForm(r *http.Request) (*Packet, error) {
 // SendPacket - Use this function to send packets
 // to a specific server through a flooding algorithm
 func (net *Network) SendPacket(p *Packet) error {
-	// Create the client and the request
-	client := &http.Client{}
-
-	// Craft form values for request
-	formValues := p.SerializeToForm()
-
 	// Debug Send SendPacket
-	fmt.Println("SendPacket DEBUG information:")
-	fmt.Println("=============================")
-	fmt.Printf("Source ID: %v\n", p.SourceID)
-	fmt.Printf("Destination ID: %v\n", p.DestinationID)
-	fmt.Printf("My ID: %v\n", net.MyID)
-	fmt.Printf("Source IP: %v\n", p.SourceIP)
-	fmt.Printf("Destination IP: %v\n", p.DestinationIP)
-	fmt.Printf("My IP: %v\n", p.DestinationIP)
-	fmt.Printf("Type: %v\n", p.Type)
-	fmt.Println("=============================")
+	debugln("SendPacket DEBUG information:")
+	debugln("=============================")
+	debugf("Source ID: %v\n", p.SourceID)
+	debugf("Destination ID: %v\n", p.DestinationID)
+	debugf("My ID: %v\n", net.MyID)
+	debugf("Source IP: %v\n", p.SourceIP)
+	debugf("Destination IP: %v\n", p.DestinationIP)
+	debugf("My IP: %v\n", p.DestinationIP)
+	debugf("Type: %v\n", p.Type)
+	debugln("=============================")
 
 	// Loop through and broadcast the packet
 	for i := range net.Nodes {
-		// Craft the request
-		req, err := http.NewRequest("POST", "http://"+net.Nodes[i].IPAddr+"/"+p.Type, strings.NewReader(formValues.Encode()))
-		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-		req.Header.Add("Content-Length", strconv.Itoa(len(formValues.Encode())))
-		fmt.Printf("URL: %s://%s%s\n", req.URL.Scheme, req.URL.Host, req.URL.Path)
-		if err != nil {
-			return err
-		}
-
-		// Send the request
-		_, err = client.Do(req)
+		_, err := net.transport().Send(net.Nodes[i].IPAddr, p)
 		if err != nil {
 			return err
 		}
@@ -241,45 +276,26 @@ func (net *Network) SendPacket(p *Packet) error {
 // to deal with going through the entire network.
 // The IP addresss to send it to is specified in the p.DestinationIP
 func (net *Network) SendPacketDirectly(p *Packet) (*http.Response, error) {
-	// Create the client
-	client := &http.Client{}
-
-	// Craft the form values for the request
-	formValues := p.SerializeToForm()
-
 	// Debug Send SendPacketDirectly
-	fmt.Println("SendPacketDirectly DEBUG information:")
-	fmt.Println("=============================")
-	fmt.Printf("Source ID: %v\n", p.SourceID)
-	fmt.Printf("Destination ID: %v\n", p.DestinationID)
-	fmt.Printf("My ID: %v\n", net.MyID)
-	fmt.Printf("Source IP: %v\n", p.SourceIP)
-	fmt.Printf("Destination IP: %v\n", p.DestinationIP)
-	fmt.Printf("My IP: %v\n", p.DestinationIP)
-	fmt.Printf("Type: %v\n", p.Type)
-	fmt.Println("=============================")
-
-	// Craft the request
-	req, err := http.NewRequest("POST", "http://"+p.DestinationIP+"/"+p.Type, strings.NewReader(formValues.Encode()))
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Add("Content-Length", strconv.Itoa(len(formValues.Encode())))
-	fmt.Printf("URL: %s://%s%s\n", req.URL.Scheme, req.URL.Host, req.URL.Path)
-	if err != nil {
-		return nil, err
-	}
+	debugln("SendPacketDirectly DEBUG information:")
+	debugln("=============================")
+	debugf("Source ID: %v\n", p.SourceID)
+	debugf("Destination ID: %v\n", p.DestinationID)
+	debugf("My ID: %v\n", net.MyID)
+	debugf("Source IP: %v\n", p.SourceIP)
+	debugf("Destination IP: %v\n", p.DestinationIP)
+	debugf("My IP: %v\n", p.DestinationIP)
+	debugf("Type: %v\n", p.Type)
+	debugln("=============================")
 
 	// Send the request
-	resp, err := client.Do(req)
-	return resp, err
+	return net.transport().Send(p.DestinationIP, p)
 }
 
 // BroadcastPacket - Use this function to
 // broadcast a packet to all other nodes in
 // a network
 func (net *Network) BroadcastPacket(p Packet) error {
-	// Create the client and the request
-	client := &http.Client{}
-
 	// Loop through and broadcast the packet
 	for i := range net.Nodes {
 		// Change the correct parameters of
@@ -288,32 +304,20 @@ func (net *Network) BroadcastPacket(p Packet) error {
 		p.DestinationIP = net.Nodes[i].IPAddr
 		p.SendType = PacketBroadCast
 
-		// Craft form values for request
-		formValues := p.SerializeToForm()
-
 		// Debug Send BroadcastPacket
-		fmt.Println("BroadcastPacket DEBUG information:")
-		fmt.Println("==================================")
-		fmt.Printf("Source ID: %v\n", p.SourceID)
-		fmt.Printf("Destination ID: %v\n", p.DestinationID)
-		fmt.Printf("My ID: %v\n", net.MyID)
-		fmt.Printf("Source IP: %v\n", p.SourceIP)
-		fmt.Printf("Destination IP: %v\n", p.DestinationIP)
-		fmt.Printf("My IP: %v\n", p.DestinationIP)
-		fmt.Printf("Type: %v\n", p.Type)
-		fmt.Println("==================================")
-
-		// Craft the request
-		req, err := http.NewRequest("POST", "http://"+net.Nodes[i].IPAddr+"/"+p.Type, strings.NewReader(formValues.Encode()))
-		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-		req.Header.Add("Content-Length", strconv.Itoa(len(formValues.Encode())))
-		fmt.Printf("URL: %s://%s%s\n", req.URL.Scheme, req.URL.Host, req.URL.Path)
-		if err != nil {
-			return err
-		}
+		debugln("BroadcastPacket DEBUG information:")
+		debugln("==================================")
+		debugf("Source ID: %v\n", p.SourceID)
+		debugf("Destination ID: %v\n", p.DestinationID)
+		debugf("My ID: %v\n", net.MyID)
+		debugf("Source IP: %v\n", p.SourceIP)
+		debugf("Destination IP: %v\n", p.DestinationIP)
+		debugf("My IP: %v\n", p.DestinationIP)
+		debugf("Type: %v\n", p.Type)
+		debugln("==================================")
 
 		// Send the request
-		_, err = client.Do(req)
+		_, err := net.transport().Send(net.Nodes[i].IPAddr, &p)
 		if err != nil {
 			return err
 		}
@@ -11,25 +11,29 @@ var wg sync.WaitGroup
 
 const (
 	MaxPeerCount = 1024
+	PingCount    = 100
 )
 
-func tortureNetwork(peers []*network.Network) {
-	for i := 0; i < 10000; i++ {
-		peers[i].Ping(peers[mrand.Intn(MaxPeerCount)].MyID)
+func tortureNetwork(sim *network.Simulator) {
+	for i := 0; i < PingCount; i++ {
+		peer := sim.Nodes[i%len(sim.Nodes)]
+		peer.Ping(sim.Nodes[mrand.Intn(len(sim.Nodes))].MyID)
+		sim.RunUntilIdle()
 	}
 }
 
 func main() {
-	// Initialize multiple peers
-	var peers []*network.Network
-	for i := 0; i < MaxPeerCount; i++ {
-		peers = append(peers, network.MakeNetwork())
-		err := peers[i].Join("127.0.0.1"+network.Port, 10000+i)
+	// Initialize multiple peers in one process. They all join
+	// through the first one
+	sim := network.MakeSimulator(MaxPeerCount)
+	for i := 1; i < MaxPeerCount; i++ {
+		err := sim.Join(i, 0)
 		if err != nil {
 			fmt.Printf("Error occured making peer: %d\n", i)
 		}
 	}
 
-	tortureNetwork(peers)
+	tortureNetwork(sim)
 
+	fmt.Println(sim)
 }
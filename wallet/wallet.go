@@ -3,38 +3,178 @@ package wallet
 import (
 	"Blockchain/blockchain"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	crand "crypto/rand"
+	"errors"
 	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secp256k1ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
 )
 
-// Wallet - wallet data
+// Wallet - wallet data. P-384 and secp256k1 keys live in KeyPair,
+// Ed25519 keys in Ed25519Key
 type Wallet struct {
-	KeyPair *ecdsa.PrivateKey `json:"KeyPair"`
+	KeyType    uint32             `json:"KeyType"`
+	KeyPair    *ecdsa.PrivateKey  `json:"KeyPair"`
+	Ed25519Key ed25519.PrivateKey `json:"Ed25519Key"`
 }
 
-// MakeWallet - Generate a wallet and return it
+// MakeWallet - Generate a P-384 wallet and return it
 func MakeWallet() (Wallet, error) {
-	privKey, err := ecdsa.GenerateKey(elliptic.P384(), crand.Reader)
-	w := Wallet{KeyPair: privKey}
-	if err != nil {
-		return w, err
+	return MakeWalletWithKeyType(blockchain.KeyP384)
+}
+
+// MakeWalletWithKeyType - Generate a wallet with a key of the given
+// type (blockchain.KeyP384, KeyEd25519 or KeySecp256k1)
+func MakeWalletWithKeyType(keyType uint32) (Wallet, error) {
+	w := Wallet{KeyType: keyType}
+	var err error
+	switch keyType {
+	case blockchain.KeyP384:
+		w.KeyPair, err = ecdsa.GenerateKey(elliptic.P384(), crand.Reader)
+	case blockchain.KeyEd25519:
+		_, w.Ed25519Key, err = ed25519.GenerateKey(crand.Reader)
+	case blockchain.KeySecp256k1:
+		var key *secp256k1.PrivateKey
+		key, err = secp256k1.GeneratePrivateKey()
+		if err == nil {
+			w.KeyPair = key.ToECDSA()
+		}
+	default:
+		err = errors.New("MakeWalletWithKeyType: unknown key type")
 	}
+	return w, err
+}
 
-	return w, nil
+// PublicKey - Returns the account of the wallet, which goes in the
+// XInput/YInput of the transactions it signs
+func (w *Wallet) PublicKey() *ecdsa.PublicKey {
+	if w.KeyType == blockchain.KeyEd25519 {
+		return blockchain.Ed25519AccountID(w.Ed25519Key.Public().(ed25519.PublicKey))
+	}
+	return &w.KeyPair.PublicKey
+}
+
+// Address - Returns the address to give out for receiving coins
+func (w *Wallet) Address() string {
+	return blockchain.PublicKeyAddress(w.PublicKey())
+}
+
+// signHash - Signs a hash with the key of the wallet, using the
+// signature scheme of its key type
+func (w *Wallet) signHash(hash []byte) (*big.Int, *big.Int, error) {
+	switch w.KeyType {
+	case blockchain.KeyEd25519:
+		sig := ed25519.Sign(w.Ed25519Key, hash)
+		half := ed25519.SignatureSize / 2
+		return new(big.Int).SetBytes(sig[:half]), new(big.Int).SetBytes(sig[half:]), nil
+	case blockchain.KeySecp256k1:
+		// A compact signature is a recovery byte followed by r and s
+		key := secp256k1.PrivKeyFromBytes(w.KeyPair.D.FillBytes(make([]byte, 32)))
+		sig := secp256k1ecdsa.SignCompact(key, hash, false)
+		return new(big.Int).SetBytes(sig[1:33]), new(big.Int).SetBytes(sig[33:]), nil
+	}
+	return ecdsa.Sign(crand.Reader, w.KeyPair, hash)
+}
+
+// requireP384 - Multisig policies, scripts, blocks and payment
+// channels only take P-384 keys
+func (w *Wallet) requireP384(caller string) error {
+	if w.KeyType != blockchain.KeyP384 {
+		return errors.New(caller + ": needs a P-384 wallet")
+	}
+	return nil
 }
 
 // SignTransaction - Signs the transaction using a private key,
 // sets the signature of the transaction to the one computed
-// in the function, and returns the signature of the transaction
+// in the function, and returns the signature of the transaction.
+// The KeyType of the transaction has to match the wallet
 // NOTE: ALWAYS CHECK FOR ERRORS ON THIS FUNCTION. OTHERWISE,
 // USING THE VALUES IT LEAVES WILL LEAD TO A SEGFAULT
 func (w *Wallet) SignTransaction(t *blockchain.Transaction) (*big.Int, *big.Int, error) {
+	if t.KeyType != w.KeyType {
+		return nil, nil, errors.New("SignTransaction: transaction key type doesn't match the wallet")
+	}
+
 	// Create a signature
-	r, s, err := ecdsa.Sign(crand.Reader, w.KeyPair, t.HashTransaction())
+	r, s, err := w.signHash(t.SigHash())
 	if err != nil {
 		return nil, nil, err
 	}
 
 	return r, s, nil
 }
+
+// SignMultisigTransaction - Signs a transaction spending from a
+// multisig account this wallet holds one of the keys of. Append
+// the signature to the Signatures of the transaction
+func (w *Wallet) SignMultisigTransaction(t *blockchain.Transaction) (blockchain.TXSignature, error) {
+	if err := w.requireP384("SignMultisigTransaction"); err != nil {
+		return blockchain.TXSignature{}, err
+	}
+	r, s, err := ecdsa.Sign(crand.Reader, w.KeyPair, t.SigHash())
+	if err != nil {
+		return blockchain.TXSignature{}, err
+	}
+
+	return blockchain.TXSignature{
+		XSigner:    w.KeyPair.X,
+		YSigner:    w.KeyPair.Y,
+		RSignature: r,
+		SSignature: s,
+	}, nil
+}
+
+// SignBlock - Signs a block with the wallet's key pair so it can
+// be sealed under a signing consensus engine such as
+// blockchain.ProofOfAuthority
+func (w *Wallet) SignBlock(b *blockchain.Block) error {
+	if err := w.requireP384("SignBlock"); err != nil {
+		return err
+	}
+	return b.SignBlock(w.KeyPair)
+}
+
+// SignScript - Signs a transaction spending from a script account
+// and returns the signature encoded for an unlocking script
+func (w *Wallet) SignScript(t *blockchain.Transaction) ([]byte, error) {
+	if err := w.requireP384("SignScript"); err != nil {
+		return nil, err
+	}
+	r, s, err := ecdsa.Sign(crand.Reader, w.KeyPair, t.SigHash())
+	if err != nil {
+		return nil, err
+	}
+
+	return blockchain.MarshalScriptSignature(r, s), nil
+}
+
+// SignChannelState - Signs a payment channel state. The sender of the
+// channel puts the signature in RSender/SSender, and the recipient
+// puts it in RRecipient/SRecipient for a cooperative close
+func (w *Wallet) SignChannelState(s *blockchain.ChannelState) (*big.Int, *big.Int, error) {
+	if err := w.requireP384("SignChannelState"); err != nil {
+		return nil, nil, err
+	}
+	r, sig, err := ecdsa.Sign(crand.Reader, w.KeyPair, s.HashChannelState())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return r, sig, nil
+}
+
+// MatchFilter - Returns true if a block filter might contain the
+// wallet's public key, or any of the other accounts it watches
+// (multisig or script accounts, for example). Only fetch the
+// blocks that match
+func (w *Wallet) MatchFilter(f *blockchain.BlockFilter, watched ...*ecdsa.PublicKey) bool {
+	items := [][]byte{blockchain.FilterItem(w.PublicKey())}
+	for _, pubKey := range watched {
+		items = append(items, blockchain.FilterItem(pubKey))
+	}
+	return f.MatchAny(items)
+}
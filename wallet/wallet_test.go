@@ -0,0 +1,92 @@
+package wallet
+
+import (
+	"Blockchain/blockchain"
+	"testing"
+)
+
+// signedTransfer - Returns a transfer from the wallet, signed by it
+func signedTransfer(t *testing.T, w *Wallet, amount float64) blockchain.Transaction {
+	t.Helper()
+	to, err := MakeWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := blockchain.Transaction{
+		Type:    blockchain.TXTransfer,
+		KeyType: w.KeyType,
+		XInput:  w.PublicKey().X,
+		YInput:  w.PublicKey().Y,
+		XOutput: to.PublicKey().X,
+		YOutput: to.PublicKey().Y,
+		Amount:  amount,
+	}
+	tx.RSignature, tx.SSignature, err = w.SignTransaction(&tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestSignTransactionKeyTypes(t *testing.T) {
+	keyTypes := map[string]uint32{
+		"P-384":     blockchain.KeyP384,
+		"Ed25519":   blockchain.KeyEd25519,
+		"secp256k1": blockchain.KeySecp256k1,
+	}
+	for name, keyType := range keyTypes {
+		w, err := MakeWalletWithKeyType(keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := signedTransfer(t, &w, 1)
+		if !tx.TransactionSignatureIsValid() {
+			t.Fatalf("%s: signature rejected", name)
+		}
+
+		changed := tx
+		changed.Amount = 2
+		if changed.TransactionSignatureIsValid() {
+			t.Fatalf("%s: signature valid for a different amount", name)
+		}
+
+		// The signature only checks out under its own scheme
+		for _, other := range keyTypes {
+			if other == keyType {
+				continue
+			}
+			relabelled := tx
+			relabelled.KeyType = other
+			if relabelled.TransactionSignatureIsValid() {
+				t.Fatalf("%s: signature valid under key type %d", name, other)
+			}
+		}
+
+		mismatch := tx
+		mismatch.KeyType = (keyType + 1) % 3
+		if _, _, err := w.SignTransaction(&mismatch); err == nil {
+			t.Fatalf("%s: signed a transaction of another key type", name)
+		}
+	}
+
+	if _, err := MakeWalletWithKeyType(3); err == nil {
+		t.Fatal("wallet with an unknown key type made")
+	}
+}
+
+func TestP384OnlySigning(t *testing.T) {
+	w, err := MakeWalletWithKeyType(blockchain.KeyEd25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := blockchain.Transaction{}
+	if _, err := w.SignMultisigTransaction(&tx); err == nil {
+		t.Fatal("Ed25519 wallet signed for a multisig account")
+	}
+	if _, err := w.SignScript(&tx); err == nil {
+		t.Fatal("Ed25519 wallet signed a script")
+	}
+	if err := w.SignBlock(&blockchain.Block{}); err == nil {
+		t.Fatal("Ed25519 wallet signed a block")
+	}
+}